@@ -0,0 +1,151 @@
+package dbd
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"math/big"
+	"database/sql/driver"
+)
+
+//	Plain decimal syntax (big.Rat also accepts "0x10", "0b101", "1_000" and fractions "1/4")
+var decimal_syntax = regexp.MustCompile(`^[+-]?\d+(\.\d+)?([eE][+-]?\d+)?$`)
+
+type (
+	//	Exact decimal value (DECIMAL columns) without float rounding
+	Decimal struct {
+		rat		*big.Rat
+	}
+	
+	Null_decimal struct {
+		Decimal	Decimal
+		Valid	bool
+	}
+)
+
+func New_decimal(s string) (Decimal, error){
+	if !decimal_syntax.MatchString(s) {
+		return Decimal{}, fmt.Errorf("Invalid decimal: %s", s)
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return Decimal{}, fmt.Errorf("Invalid decimal: %s", s)
+	}
+	return Decimal{r}, nil
+}
+
+func Must_decimal(s string) Decimal {
+	d, err := New_decimal(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func Decimal_int(i int64) Decimal {
+	return Decimal{new(big.Rat).SetInt64(i)}
+}
+
+//	Copy of the underlying value
+func (d Decimal) Rat() *big.Rat {
+	return new(big.Rat).Set(d.rat_value())
+}
+
+func (d Decimal) Cmp(x Decimal) int {
+	return d.rat_value().Cmp(x.rat_value())
+}
+
+func (d Decimal) Sign() int {
+	return d.rat_value().Sign()
+}
+
+//	Number of digits after the decimal point
+func (d Decimal) Scale() int {
+	n, _ := d.rat_value().FloatPrec()
+	return n
+}
+
+func (d Decimal) String() string {
+	return d.rat_value().FloatString(d.Scale())
+}
+
+//	Format with a fixed number of digits after the decimal point (rounded half away from zero)
+func (d Decimal) Fixed(scale int) string {
+	return d.rat_value().FloatString(scale)
+}
+
+//	Check the value fits in DECIMAL(precision,scale)
+func (d Decimal) Validate(precision, scale int, unsigned bool) error {
+	r := d.rat_value()
+	if unsigned && r.Sign() < 0 {
+		return fmt.Errorf("Decimal %s is negative in unsigned column", d)
+	}
+	if d.Scale() > scale {
+		return fmt.Errorf("Decimal %s exceeds scale %d", d, scale)
+	}
+	
+	//	Digits before the decimal point
+	var digits int
+	if i := new(big.Int).Quo(r.Num(), r.Denom()); i.Sign() != 0 {
+		digits = len(i.Abs(i).String())
+	}
+	if digits > precision-scale {
+		return fmt.Errorf("Decimal %s exceeds precision (%d,%d)", d, precision, scale)
+	}
+	return nil
+}
+
+//	Implements driver.Valuer
+func (d Decimal) Value() (driver.Value, error){
+	return d.String(), nil
+}
+
+//	Implements sql.Scanner
+func (d *Decimal) Scan(src any) error {
+	var s string
+	switch v := src.(type) {
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	case int64:
+		*d = Decimal_int(v)
+		return nil
+	case float64:
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	case nil:
+		return fmt.Errorf("Unable to scan NULL into Decimal")
+	default:
+		return fmt.Errorf("Unable to scan %T into Decimal", src)
+	}
+	
+	v, err := New_decimal(s)
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+func (n Null_decimal) Value() (driver.Value, error){
+	if !n.Valid {
+		return nil, nil
+	}
+	return n.Decimal.Value()
+}
+
+func (n *Null_decimal) Scan(src any) error {
+	if src == nil {
+		n.Decimal, n.Valid = Decimal{}, false
+		return nil
+	}
+	n.Valid = true
+	return n.Decimal.Scan(src)
+}
+
+func (d Decimal) rat_value() *big.Rat {
+	if d.rat == nil {
+		return new(big.Rat)
+	}
+	return d.rat
+}
\ No newline at end of file
@@ -0,0 +1,120 @@
+package dbd
+
+/*
+	Test
+	# go test . -v
+*/
+
+import (
+	"testing"
+)
+
+func Test_decimal(t *testing.T){
+	t.Run("parse and format", func(t *testing.T){
+		for input, want := range map[string]string{
+			"12.340":						"12.34",
+			"-0.10":						"-0.1",
+			"100":							"100",
+			"0":							"0",
+			"1e-3":							"0.001",
+			"99999999999999999999.99":		"99999999999999999999.99",
+			"0.1000000000000000055511":		"0.1000000000000000055511",
+		}{
+			d, err := New_decimal(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := d.String(); got != want {
+				t.Fatalf("Decimal %s want: %s got: %s", input, want, got)
+			}
+		}
+		
+		for _, input := range []string{"", "abc", "1/3", "1/4", "1.2.3", "0x10", "0b101", "0o17", "1_000", ".5", "5.", " 1", "1e", "Inf", "NaN"} {
+			if _, err := New_decimal(input); err == nil {
+				t.Fatalf("Decimal %q must be invalid", input)
+			}
+		}
+		
+		if got := Must_decimal("2.675").Fixed(2); got != "2.68" {
+			t.Fatalf("Fixed want: 2.68 got: %s", got)
+		}
+		if got := (Decimal{}).String(); got != "0" {
+			t.Fatalf("Zero value want: 0 got: %s", got)
+		}
+	})
+	
+	t.Run("validate", func(t *testing.T){
+		for _, test := range []struct{
+			value		string
+			unsigned	bool
+			valid		bool
+		}{
+			{"99999999.99", false, true},
+			{"-99999999.99", false, true},
+			{"100000000", false, false},
+			{"0.001", false, false},
+			{"1.5", true, true},
+			{"-1.5", true, false},
+			{"0.99", false, true},
+		}{
+			err := Must_decimal(test.value).Validate(10, 2, test.unsigned)
+			if test.valid != (err == nil) {
+				t.Fatalf("Decimal %s (10,2) unsigned=%t valid want: %t got: %v", test.value, test.unsigned, test.valid, err)
+			}
+		}
+	})
+	
+	t.Run("scan and value", func(t *testing.T){
+		var d Decimal
+		for src, want := range map[any]string{
+			"1234567890123456789.123456789":	"1234567890123456789.123456789",
+			int64(42):							"42",
+			0.5:								"0.5",
+		}{
+			if err := d.Scan(src); err != nil {
+				t.Fatal(err)
+			}
+			if got := d.String(); got != want {
+				t.Fatalf("Scan %v want: %s got: %s", src, want, got)
+			}
+		}
+		if err := d.Scan([]byte("0.30")); err != nil || d.Cmp(Must_decimal("0.3")) != 0 {
+			t.Fatalf("Scan []byte got: %s %v", d, err)
+		}
+		if err := d.Scan(nil); err == nil {
+			t.Fatalf("Scan NULL into Decimal must fail")
+		}
+		
+		var n Null_decimal
+		if err := n.Scan(nil); err != nil || n.Valid {
+			t.Fatalf("Scan NULL into Null_decimal got: %v %v", n, err)
+		}
+		if v, _ := n.Value(); v != nil {
+			t.Fatalf("Null_decimal value want: nil got: %v", v)
+		}
+		
+		v, err := Must_decimal("10.50").Value()
+		if err != nil || v != "10.5" {
+			t.Fatalf("Value want: 10.5 got: %v", v)
+		}
+	})
+	
+	t.Run("column range", func(t *testing.T){
+		col_schema, _ := parse_schema_column("decimal(20,4) unsigned", false)
+		r := col_schema.Range_decimal()
+		if r.Min.String() != "0" || r.Max.String() != "9999999999999999.9999" {
+			t.Fatalf("Range want: 0 - 9999999999999999.9999 got: %s - %s", r.Min, r.Max)
+		}
+		if err := col_schema.Validate_decimal(Must_decimal("9999999999999999.9999")); err != nil {
+			t.Fatal(err)
+		}
+		if err := col_schema.Validate_decimal(Must_decimal("10000000000000000")); err == nil {
+			t.Fatalf("Decimal out of range must fail")
+		}
+		
+		col_schema, _ = parse_schema_column("decimal(5,2)", false)
+		if r := col_schema.Range_decimal(); r.Min.String() != "-999.99" {
+			t.Fatalf("Range min want: -999.99 got: %s", r.Min)
+		}
+	})
+}
\ No newline at end of file
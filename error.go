@@ -4,6 +4,12 @@ import (
 	"errors"
 	"context"
 	"database/sql"
+	"github.com/go-sql-driver/mysql"
+)
+
+const (
+	mysql_lock_wait_timeout	= 1205
+	mysql_lock_nowait		= 3572
 )
 
 var ErrNotFound = errors.New("Not found")
@@ -18,6 +24,12 @@ type (
 		error 	string
 		stack 	string
 	}
+	
+	//	Row lock not available (NOWAIT) or lock wait timeout
+	Lock_error struct {
+		error 	string
+		stack 	string
+	}
 )
 
 func No_rows_error(err error) bool {
@@ -32,6 +44,15 @@ func (e *Timeout_error) Error() string {
 	return e.error
 }
 
+func (e *Lock_error) Error() string {
+	return e.error
+}
+
+func lock_unavailable(err error) bool {
+	var e *mysql.MySQLError
+	return errors.As(err, &e) && (e.Number == mysql_lock_nowait || e.Number == mysql_lock_wait_timeout)
+}
+
 func ctx_canceled(err error) bool {
 	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
 }
\ No newline at end of file
@@ -0,0 +1,30 @@
+package dbd
+
+/*
+	Test
+	# go test . -v
+*/
+
+import (
+	"fmt"
+	"errors"
+	"testing"
+	"github.com/go-sql-driver/mysql"
+)
+
+func Test_lock_error(t *testing.T){
+	t.Run("lock unavailable", func(t *testing.T){
+		for _, number := range []uint16{1205, 3572} {
+			err := fmt.Errorf("query: %w", &mysql.MySQLError{Number: number})
+			if !lock_unavailable(err) {
+				t.Fatalf("Lock unavailable want: true got: false (%d)", number)
+			}
+		}
+		if lock_unavailable(&mysql.MySQLError{Number: 1062}) {
+			t.Fatalf("Lock unavailable want: false got: true (1062)")
+		}
+		if lock_unavailable(errors.New("test")) {
+			t.Fatalf("Lock unavailable want: false got: true")
+		}
+	})
+}
\ No newline at end of file
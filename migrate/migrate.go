@@ -0,0 +1,391 @@
+package migrate
+
+import (
+	"io"
+	"fmt"
+	"time"
+	"slices"
+	"context"
+	"io/fs"
+	"database/sql"
+	"github.com/clarkk/go-dbd"
+	"github.com/clarkk/go-dbd/sqlc"
+)
+
+const (
+	TABLE			= "schema_migration"
+	LOCK_NAME		= "dbd_migrate"
+	LOCK_TIMEOUT	= 30
+)
+
+type (
+	Migrate_func func(ctx context.Context, tx *sql.Tx) error
+	
+	Migrator struct {
+		fsys			fs.FS
+		funcs			[]*Migration
+		table			string
+		lock_name		string
+		lock_timeout	int
+	}
+	
+	Migration struct {
+		Version			uint64
+		Name			string
+		Checksum		string
+		up_sql			[]string
+		down_sql		[]string
+		up				Migrate_func
+		down			Migrate_func
+	}
+)
+
+//	Migrations are read from the root of fsys (use fs.Sub for embedded sub directories)
+func New(fsys fs.FS) *Migrator {
+	return &Migrator{
+		fsys:			fsys,
+		table:			TABLE,
+		lock_name:		LOCK_NAME,
+		lock_timeout:	LOCK_TIMEOUT,
+	}
+}
+
+//	Register Go migration
+func (m *Migrator) Register(version uint64, name string, up, down Migrate_func) *Migrator {
+	m.funcs = append(m.funcs, &Migration{
+		Version:	version,
+		Name:		name,
+		Checksum:	checksum([]byte("go:"+name)),
+		up:			up,
+		down:		down,
+	})
+	return m
+}
+
+func (m *Migrator) Table(table string) *Migrator {
+	m.table = table
+	return m
+}
+
+//	Lock timeout in seconds
+func (m *Migrator) Lock(name string, timeout int) *Migrator {
+	m.lock_name		= name
+	m.lock_timeout	= timeout
+	return m
+}
+
+//	Ordered list of all migrations
+func (m *Migrator) Migrations() ([]*Migration, error){
+	list := map[uint64]*Migration{}
+	if m.fsys != nil {
+		if err := load_source(m.fsys, list); err != nil {
+			return nil, err
+		}
+	}
+	for _, f := range m.funcs {
+		if _, found := list[f.Version]; found {
+			return nil, fmt.Errorf("Duplicate migration version: %d", f.Version)
+		}
+		list[f.Version] = f
+	}
+	
+	migrations := make([]*Migration, 0, len(list))
+	for _, migration := range list {
+		if migration.up_sql == nil && migration.up == nil {
+			return nil, fmt.Errorf("Missing up migration: %d", migration.Version)
+		}
+		migrations = append(migrations, migration)
+	}
+	slices.SortFunc(migrations, func(a, b *Migration) int {
+		if a.Version < b.Version {
+			return -1
+		}
+		if a.Version > b.Version {
+			return 1
+		}
+		return 0
+	})
+	return migrations, nil
+}
+
+//	Apply all pending migrations
+func (m *Migrator) Up(ctx context.Context) (int, error){
+	migrations, err := m.Migrations()
+	if err != nil {
+		return 0, err
+	}
+	
+	conn, err := m.lock(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer m.unlock(conn)
+	
+	if _, err = conn.ExecContext(ctx, m.create_table_sql()); err != nil {
+		return 0, fmt.Errorf("Unable to create migration table: %w", err)
+	}
+	
+	pending, err := m.pending(ctx, conn, migrations)
+	if err != nil {
+		return 0, err
+	}
+	
+	for i, migration := range pending {
+		if err = m.apply(ctx, conn, migration, true); err != nil {
+			m.refresh_schema(i)
+			return i, err
+		}
+	}
+	
+	m.refresh_schema(len(pending))
+	return len(pending), nil
+}
+
+//	Revert the latest applied migrations
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error){
+	migrations, err := m.Migrations()
+	if err != nil {
+		return 0, err
+	}
+	
+	conn, err := m.lock(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer m.unlock(conn)
+	
+	applied, err := m.applied(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+	if err = verify(migrations, applied); err != nil {
+		return 0, err
+	}
+	
+	var reverted int
+	for i := len(migrations) - 1; i >= 0 && reverted < steps; i-- {
+		migration := migrations[i]
+		if _, found := applied[migration.Version]; !found {
+			continue
+		}
+		if migration.down_sql == nil && migration.down == nil {
+			m.refresh_schema(reverted)
+			return reverted, fmt.Errorf("Missing down migration: %d", migration.Version)
+		}
+		if err = m.apply(ctx, conn, migration, false); err != nil {
+			m.refresh_schema(reverted)
+			return reverted, err
+		}
+		reverted++
+	}
+	
+	m.refresh_schema(reverted)
+	return reverted, nil
+}
+
+//	Write pending statements without applying them
+func (m *Migrator) Dry_run(ctx context.Context, w io.Writer) error {
+	migrations, err := m.Migrations()
+	if err != nil {
+		return err
+	}
+	
+	conn, err := dbd.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	
+	pending := migrations
+	var exists int
+	if err = conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM information_schema.TABLES WHERE TABLE_SCHEMA=DATABASE() AND TABLE_NAME=?", m.table).Scan(&exists); err != nil {
+		return fmt.Errorf("Unable to lookup migration table: %w", err)
+	}
+	if exists != 0 {
+		if pending, err = m.pending(ctx, conn, migrations); err != nil {
+			return err
+		}
+	}
+	
+	for _, migration := range pending {
+		fmt.Fprintf(w, "-- %d %s\n", migration.Version, migration.Name)
+		if migration.up != nil {
+			fmt.Fprint(w, "-- (Go migration)\n\n")
+			continue
+		}
+		for _, stmt := range migration.up_sql {
+			fmt.Fprintf(w, "%s;\n", stmt)
+		}
+		fmt.Fprint(w, "\n")
+	}
+	return nil
+}
+
+func (m *Migrator) pending(ctx context.Context, conn *sql.Conn, migrations []*Migration) ([]*Migration, error){
+	applied, err := m.applied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	if err = verify(migrations, applied); err != nil {
+		return nil, err
+	}
+	
+	pending := make([]*Migration, 0, len(migrations))
+	for _, migration := range migrations {
+		if _, found := applied[migration.Version]; !found {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+//	Applied versions and checksums
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[uint64]string, error){
+	query := sqlc.Select(m.table).
+		Select([]string{
+			"version",
+			"checksum",
+		})
+	
+	sql, data, err := query.Compile()
+	if err != nil {
+		return nil, err
+	}
+	
+	rows, err := conn.QueryContext(ctx, sql, data...)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to fetch applied migrations: %w", err)
+	}
+	defer rows.Close()
+	
+	applied := map[uint64]string{}
+	for rows.Next() {
+		var (
+			version		uint64
+			checksum	string
+		)
+		if err = rows.Scan(&version, &checksum); err != nil {
+			return nil, fmt.Errorf("Unable to fetch applied migrations: %w", err)
+		}
+		applied[version] = checksum
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("Unable to fetch applied migrations: %w", err)
+	}
+	return applied, nil
+}
+
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration *Migration, up bool) error {
+	var (
+		stmts	= migration.down_sql
+		fn		= migration.down
+		track	sqlc.SQL
+	)
+	if up {
+		stmts	= migration.up_sql
+		fn		= migration.up
+		track	= sqlc.Insert(m.table).
+			Fields(sqlc.Map{
+				"version":		migration.Version,
+				"name":			migration.Name,
+				"checksum":		migration.Checksum,
+				"time":			time.Now().Unix(),
+			})
+	} else {
+		track = sqlc.Delete(m.table).
+			Where(sqlc.Where().
+				Eq("version", migration.Version),
+			)
+	}
+	
+	//	MySQL/MariaDB implicitly commits DDL statements, so the transaction only protects DML
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("Migration %d begin: %w", migration.Version, err)
+	}
+	defer tx.Rollback()
+	
+	if fn != nil {
+		if err = fn(ctx, tx); err != nil {
+			return fmt.Errorf("Migration %d %s: %w", migration.Version, migration.Name, err)
+		}
+	} else {
+		for _, stmt := range stmts {
+			if _, err = tx.ExecContext(ctx, stmt); err != nil {
+				return fmt.Errorf("Migration %d %s: %w\n%s", migration.Version, migration.Name, err, stmt)
+			}
+		}
+	}
+	
+	sql, data, err := track.Compile()
+	if err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, sql, data...); err != nil {
+		return fmt.Errorf("Migration %d tracking: %w", migration.Version, err)
+	}
+	
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("Migration %d commit: %w", migration.Version, err)
+	}
+	return nil
+}
+
+//	Only one instance can migrate at a time
+func (m *Migrator) lock(ctx context.Context) (*sql.Conn, error){
+	conn, err := dbd.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	
+	var locked sql.NullInt64
+	if err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", m.lock_name, m.lock_timeout).Scan(&locked); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("Unable to get migration lock: %w", err)
+	}
+	if !locked.Valid || locked.Int64 != 1 {
+		conn.Close()
+		return nil, fmt.Errorf("Unable to get migration lock: %s (timeout %ds)", m.lock_name, m.lock_timeout)
+	}
+	return conn, nil
+}
+
+func (m *Migrator) unlock(conn *sql.Conn){
+	conn.ExecContext(context.Background(), "DO RELEASE_LOCK(?)", m.lock_name)
+	conn.Close()
+}
+
+//	Refresh the schema cache if any migrations have been applied
+func (m *Migrator) refresh_schema(changed int){
+	if changed > 0 {
+		dbd.Refresh_schema()
+	}
+}
+
+func (m *Migrator) create_table_sql() string {
+	return "CREATE TABLE IF NOT EXISTS ."+m.table+` (
+version BIGINT UNSIGNED NOT NULL,
+name VARCHAR(255) NOT NULL,
+checksum CHAR(64) NOT NULL,
+time INT UNSIGNED NOT NULL,
+PRIMARY KEY (version)
+)`
+}
+
+//	Applied migrations must be unchanged and still exist
+func verify(migrations []*Migration, applied map[uint64]string) error {
+	versions := make(map[uint64]*Migration, len(migrations))
+	for _, migration := range migrations {
+		versions[migration.Version] = migration
+	}
+	for version, sum := range applied {
+		migration, found := versions[version]
+		if !found {
+			return fmt.Errorf("Applied migration not found: %d", version)
+		}
+		if migration.Checksum != sum {
+			return fmt.Errorf("Applied migration checksum mismatch: %d %s", version, migration.Name)
+		}
+	}
+	return nil
+}
\ No newline at end of file
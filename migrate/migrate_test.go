@@ -0,0 +1,145 @@
+package migrate
+
+/*
+	Test
+	# go test . -v
+*/
+
+import (
+	"slices"
+	"context"
+	"testing"
+	"testing/fstest"
+	"database/sql"
+)
+
+func Test_source(t *testing.T){
+	t.Run("migrations ordered", func(t *testing.T){
+		fsys := fstest.MapFS{
+			"0002_add_email.up.sql":		{Data: []byte("ALTER TABLE user ADD email VARCHAR(255);")},
+			"0002_add_email.down.sql":		{Data: []byte("ALTER TABLE user DROP email;")},
+			"0001_create_user.up.sql":		{Data: []byte("CREATE TABLE user (id INT);\nCREATE INDEX i ON user (id);")},
+			"README.md":					{Data: []byte("ignored")},
+		}
+		
+		m := New(fsys).
+			Register(3, "backfill", func(ctx context.Context, tx *sql.Tx) error {
+				return nil
+			}, nil)
+		
+		migrations, err := m.Migrations()
+		if err != nil {
+			t.Fatal(err)
+		}
+		
+		var versions []uint64
+		for _, migration := range migrations {
+			versions = append(versions, migration.Version)
+		}
+		want := []uint64{1, 2, 3}
+		if !slices.Equal(versions, want) {
+			t.Fatalf("Versions want:\n%v\nVersions got:\n%v", want, versions)
+		}
+		
+		if len(migrations[0].up_sql) != 2 {
+			t.Fatalf("Statements want: 2\nStatements got: %d", len(migrations[0].up_sql))
+		}
+		if migrations[1].down_sql == nil {
+			t.Fatalf("Down migration missing")
+		}
+		if migrations[0].Checksum == "" || migrations[0].Checksum == migrations[1].Checksum {
+			t.Fatalf("Invalid checksum: %s", migrations[0].Checksum)
+		}
+		
+		//	A changed down file changes the checksum
+		fsys["0002_add_email.down.sql"] = &fstest.MapFile{Data: []byte("ALTER TABLE user DROP COLUMN email;")}
+		changed, err := New(fsys).Migrations()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if changed[1].Checksum == migrations[1].Checksum {
+			t.Fatalf("Checksum must include the down migration")
+		}
+		if changed[0].Checksum != migrations[0].Checksum {
+			t.Fatalf("Checksum want:\n%s\nChecksum got:\n%s", migrations[0].Checksum, changed[0].Checksum)
+		}
+	})
+	
+	t.Run("duplicate version", func(t *testing.T){
+		fsys := fstest.MapFS{
+			"0001_create_user.up.sql":		{Data: []byte("SELECT 1")},
+		}
+		
+		_, err := New(fsys).
+			Register(1, "go", func(ctx context.Context, tx *sql.Tx) error {
+				return nil
+			}, nil).
+			Migrations()
+		
+		want := "Duplicate migration version: 1"
+		if err == nil || err.Error() != want {
+			t.Fatalf("Error want:\n%s\nError got:\n%v", want, err)
+		}
+	})
+	
+	t.Run("missing up", func(t *testing.T){
+		fsys := fstest.MapFS{
+			"0001_create_user.down.sql":	{Data: []byte("DROP TABLE user")},
+		}
+		
+		_, err := New(fsys).Migrations()
+		
+		want := "Missing up migration: 1"
+		if err == nil || err.Error() != want {
+			t.Fatalf("Error want:\n%s\nError got:\n%v", want, err)
+		}
+	})
+}
+
+func Test_split_statements(t *testing.T){
+	script := `-- create table
+CREATE TABLE user (
+	name VARCHAR(255) DEFAULT 'a;b', # comment;
+	note TEXT /* ; */
+);
+INSERT INTO user (name) VALUES ("x\";y");
+/*!40101 SET NAMES utf8mb4 */;
+/*M!100616 SET NOTE_VERBOSITY=0 */;
+SET x=5--1;
+SELECT /*+ MAX_EXECUTION_TIME(1000) */ 1 --
+`
+	want := []string{
+		"CREATE TABLE user (\n\tname VARCHAR(255) DEFAULT 'a;b', \n\tnote TEXT  \n)",
+		`INSERT INTO user (name) VALUES ("x\";y")`,
+		"/*!40101 SET NAMES utf8mb4 */",
+		"/*M!100616 SET NOTE_VERBOSITY=0 */",
+		"SET x=5--1",
+		"SELECT /*+ MAX_EXECUTION_TIME(1000) */ 1",
+	}
+	got := split_statements(script)
+	if !slices.Equal(want, got) {
+		t.Fatalf("Statements want:\n%q\nStatements got:\n%q", want, got)
+	}
+}
+
+func Test_verify(t *testing.T){
+	migrations := []*Migration{{
+		Version:	1,
+		Name:		"create_user",
+		Checksum:	"abc",
+	}}
+	
+	if err := verify(migrations, map[uint64]string{1: "abc"}); err != nil {
+		t.Fatal(err)
+	}
+	
+	want := "Applied migration checksum mismatch: 1 create_user"
+	if err := verify(migrations, map[uint64]string{1: "def"}); err == nil || err.Error() != want {
+		t.Fatalf("Error want:\n%s\nError got:\n%v", want, err)
+	}
+	
+	want = "Applied migration not found: 2"
+	if err := verify(migrations, map[uint64]string{2: "abc"}); err == nil || err.Error() != want {
+		t.Fatalf("Error want:\n%s\nError got:\n%v", want, err)
+	}
+}
\ No newline at end of file
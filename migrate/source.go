@@ -0,0 +1,185 @@
+package migrate
+
+import (
+	"fmt"
+	"io/fs"
+	"regexp"
+	"strconv"
+	"strings"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+const (
+	suffix_up	= ".up.sql"
+	suffix_down	= ".down.sql"
+)
+
+var migration_file = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+//	Load "<version>_<name>.up.sql" and "<version>_<name>.down.sql" files from the root of fsys
+func load_source(fsys fs.FS, list map[uint64]*Migration) error {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return fmt.Errorf("Unable to read migrations: %w", err)
+	}
+	
+	//	Checksum of both files is set when all files are read
+	files := map[uint64][2][]byte{}
+	
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || (!strings.HasSuffix(name, suffix_up) && !strings.HasSuffix(name, suffix_down)) {
+			continue
+		}
+		
+		matches := migration_file.FindStringSubmatch(name)
+		if len(matches) == 0 {
+			return fmt.Errorf("Invalid migration file name: %s", name)
+		}
+		
+		version, err := strconv.ParseUint(matches[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("Invalid migration version: %s", name)
+		}
+		
+		b, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return fmt.Errorf("Unable to read migration %s: %w", name, err)
+		}
+		
+		m, found := list[version]
+		if !found {
+			m = &Migration{
+				Version:	version,
+				Name:		matches[2],
+			}
+			list[version] = m
+		} else if m.Name != matches[2] {
+			return fmt.Errorf("Migration version %d used by both %s and %s", version, m.Name, matches[2])
+		}
+		
+		if matches[3] == "up" {
+			if m.up_sql != nil || m.up != nil {
+				return fmt.Errorf("Duplicate up migration: %d", version)
+			}
+			m.up_sql = split_statements(string(b))
+		} else {
+			if m.down_sql != nil || m.down != nil {
+				return fmt.Errorf("Duplicate down migration: %d", version)
+			}
+			m.down_sql = split_statements(string(b))
+		}
+		
+		f := files[version]
+		if matches[3] == "up" {
+			f[0] = b
+		} else {
+			f[1] = b
+		}
+		files[version] = f
+	}
+	
+	for version, f := range files {
+		if f[1] == nil {
+			list[version].Checksum = checksum(f[0])
+		} else {
+			list[version].Checksum = checksum(f[0], f[1])
+		}
+	}
+	return nil
+}
+
+//	Checksum of the up file (and down file) separated by a zero byte
+func checksum(parts ...[]byte) string {
+	h := sha256.New()
+	for i, b := range parts {
+		if i > 0 {
+			h.Write([]byte{0})
+		}
+		h.Write(b)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+//	Split SQL script into statements on ";" outside quotes and comments
+func split_statements(script string) []string {
+	var (
+		list	[]string
+		sb		strings.Builder
+		quote	byte
+	)
+	
+	length := len(script)
+	for i := 0; i < length; i++ {
+		char := script[i]
+		
+		if quote != 0 {
+			sb.WriteByte(char)
+			if char == '\\' && quote != '`' && i+1 < length {
+				i++
+				sb.WriteByte(script[i])
+				continue
+			}
+			if char == quote {
+				quote = 0
+			}
+			continue
+		}
+		
+		switch {
+		case char == '\'' || char == '"' || char == '`':
+			quote = char
+			sb.WriteByte(char)
+		
+		//	Line comments ("--" must be followed by whitespace)
+		case char == '#' || (char == '-' && i+1 < length && script[i+1] == '-' && (i+2 == length || is_space(script[i+2]))):
+			for i < length && script[i] != '\n' {
+				i++
+			}
+			sb.WriteByte('\n')
+		
+		//	Block comments (executable comments "/*! */", "/*M! */" and optimizer hints "/*+ */" are kept)
+		case char == '/' && i+1 < length && script[i+1] == '*':
+			end := strings.Index(script[i+2:], "*/")
+			if end == -1 {
+				end = length
+			} else {
+				end += i + 4
+			}
+			if comment := script[i:end]; strings.HasPrefix(comment, "/*!") || strings.HasPrefix(comment, "/*M!") || strings.HasPrefix(comment, "/*+") {
+				sb.WriteString(comment)
+			} else {
+				sb.WriteByte(' ')
+			}
+			i = end - 1
+			
+		case char == ';':
+			if stmt := strings.TrimSpace(sb.String()); stmt != "" {
+				list = append(list, stmt)
+			}
+			sb.Reset()
+			
+		default:
+			sb.WriteByte(char)
+		}
+	}
+	
+	if stmt := strings.TrimSpace(sb.String()); stmt != "" {
+		list = append(list, stmt)
+	}
+	
+	if list == nil {
+		//	Empty script is still a (no-op) migration
+		return []string{}
+	}
+	return list
+}
+
+func is_space(char byte) bool {
+	switch char {
+	case ' ', '\t', '\n', '\r':
+		return true
+	}
+	return false
+}
\ No newline at end of file
@@ -0,0 +1,51 @@
+package dbd
+
+import (
+	"context"
+	"database/sql"
+	"github.com/go-errors/errors"
+	"github.com/clarkk/go-dbd/sqlc"
+)
+
+type Count_query interface {
+	sqlc.SQL
+	Count_query() *sqlc.Count_query
+}
+
+//	Fetch a page of rows and the total number of rows
+func Paginate[T any](ctx context.Context, query Count_query, scan func(rows *sql.Rows) (T, error)) ([]T, uint64, error){
+	var total uint64
+	if _, err := Query_row(ctx, query.Count_query(), []any{&total}); err != nil {
+		return nil, 0, err
+	}
+	if total == 0 {
+		return []T{}, 0, nil
+	}
+	
+	rows, err := Query(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	
+	list := []T{}
+	for rows.Next() {
+		row, err := scan(rows)
+		if err != nil {
+			return nil, 0, &Error{"DB paginate scan: "+err.Error(), errors.Wrap(err, 0).ErrorStack()}
+		}
+		list = append(list, row)
+	}
+	if err := rows.Err(); err != nil {
+		msg		:= sqlc.SQL_error("DB paginate", query, err)
+		stack	:= errors.Wrap(err, 0).ErrorStack()
+		if ctx_canceled(err) {
+			return nil, 0, &Timeout_error{msg, stack}
+		}
+		if lock_unavailable(err) {
+			return nil, 0, &Lock_error{msg, stack}
+		}
+		return nil, 0, &Error{msg, stack}
+	}
+	return list, total, nil
+}
\ No newline at end of file
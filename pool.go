@@ -13,6 +13,7 @@ import (
 var (
 	db 			*sql.DB
 	connected 	bool
+	strict		bool
 )
 
 func Connect(dsn string, conn_cpu int){
@@ -37,6 +38,14 @@ func Connect(dsn string, conn_cpu int){
 	}
 }
 
+//	Validate all queries against the fetched schema before they are compiled
+func Strict(){
+	if db_tables == nil {
+		panic("Strict mode requires fetched schema")
+	}
+	strict = true
+}
+
 func Ping() bool {
 	if err := db.Ping(); err != nil {
 		connected = false
@@ -45,8 +54,20 @@ func Ping() bool {
 	return true
 }
 
+//	Reserve a single connection from the pool (session-level state like locks)
+func Conn(ctx context.Context) (*sql.Conn, error){
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		if ctx_canceled(err) {
+			return nil, &Timeout_error{"DB connection: "+err.Error(), errors.Wrap(err, 0).ErrorStack()}
+		}
+		return nil, &Error{"DB connection: "+err.Error(), errors.Wrap(err, 0).ErrorStack()}
+	}
+	return conn, nil
+}
+
 func Exec(ctx context.Context, query sqlc.SQL) (sql.Result, error){
-	sql, data, err := query.Compile()
+	sql, data, err := compile(query)
 	if err != nil {
 		return nil, &Error{"DB execute compile: "+err.Error(), errors.Wrap(err, 0).ErrorStack()}
 	}
@@ -62,13 +83,16 @@ func Exec(ctx context.Context, query sqlc.SQL) (sql.Result, error){
 		if ctx_canceled(err) {
 			return nil, &Timeout_error{msg, stack}
 		}
+		if lock_unavailable(err) {
+			return nil, &Lock_error{msg, stack}
+		}
 		return nil, &Error{msg, stack}
 	}
 	return result, nil
 }
 
 func Query_row(ctx context.Context, query sqlc.SQL, scan []any) (bool, error){
-	sql, data, err := query.Compile()
+	sql, data, err := compile(query)
 	if err != nil {
 		return false, &Error{"DB query row compile: "+err.Error(), errors.Wrap(err, 0).ErrorStack()}
 	}
@@ -86,13 +110,16 @@ func Query_row(ctx context.Context, query sqlc.SQL, scan []any) (bool, error){
 		if ctx_canceled(err) {
 			return false, &Timeout_error{msg, stack}
 		}
+		if lock_unavailable(err) {
+			return false, &Lock_error{msg, stack}
+		}
 		return false, &Error{msg, stack}
 	}
 	return false, nil
 }
 
 func Query(ctx context.Context, query sqlc.SQL) (*sql.Rows, error){
-	sql, data, err := query.Compile()
+	sql, data, err := compile(query)
 	if err != nil {
 		return nil, &Error{"DB query compile: "+err.Error(), errors.Wrap(err, 0).ErrorStack()}
 	}
@@ -108,6 +135,9 @@ func Query(ctx context.Context, query sqlc.SQL) (*sql.Rows, error){
 		if ctx_canceled(err) {
 			return nil, &Timeout_error{msg, stack}
 		}
+		if lock_unavailable(err) {
+			return nil, &Lock_error{msg, stack}
+		}
 		return nil, &Error{msg, stack}
 	}
 	return rows, nil
@@ -115,7 +145,7 @@ func Query(ctx context.Context, query sqlc.SQL) (*sql.Rows, error){
 
 func Insert(ctx context.Context, query sqlc.SQL) (uint64, error){
 	var id uint64
-	sql, data, err := query.Compile()
+	sql, data, err := compile(query)
 	if err != nil {
 		return id, &Error{"DB insert compile: "+err.Error(), errors.Wrap(err, 0).ErrorStack()}
 	}
@@ -130,13 +160,16 @@ func Insert(ctx context.Context, query sqlc.SQL) (uint64, error){
 		if ctx_canceled(err) {
 			return 0, &Timeout_error{msg, stack}
 		}
+		if lock_unavailable(err) {
+			return 0, &Lock_error{msg, stack}
+		}
 		return 0, &Error{msg, stack}
 	}
 	return id, nil
 }
 
 func Update(ctx context.Context, query sqlc.SQL) (sql.Result, error){
-	sql, data, err := query.Compile()
+	sql, data, err := compile(query)
 	if err != nil {
 		return nil, &Error{"DB update compile: "+err.Error(), errors.Wrap(err, 0).ErrorStack()}
 	}
@@ -152,13 +185,16 @@ func Update(ctx context.Context, query sqlc.SQL) (sql.Result, error){
 		if ctx_canceled(err) {
 			return nil, &Timeout_error{msg, stack}
 		}
+		if lock_unavailable(err) {
+			return nil, &Lock_error{msg, stack}
+		}
 		return nil, &Error{msg, stack}
 	}
 	return result, nil
 }
 
 func Delete(ctx context.Context, query sqlc.SQL) (bool, error){
-	sql, data, err := query.Compile()
+	sql, data, err := compile(query)
 	if err != nil {
 		return false, &Error{"DB delete compile: "+err.Error(), errors.Wrap(err, 0).ErrorStack()}
 	}
@@ -177,11 +213,25 @@ func Delete(ctx context.Context, query sqlc.SQL) (bool, error){
 		if ctx_canceled(err) {
 			return false, &Timeout_error{msg, stack}
 		}
+		if lock_unavailable(err) {
+			return false, &Lock_error{msg, stack}
+		}
 		return false, &Error{msg, stack}
 	}
 	return false, nil
 }
 
+func compile(query sqlc.SQL) (string, []any, error){
+	if strict {
+		if v, ok := query.(sqlc.Validator); ok {
+			if err := v.Validate(db_tables); err != nil {
+				return "", nil, err
+			}
+		}
+	}
+	return query.Compile()
+}
+
 func Close(){
 	db.Close()
 }
\ No newline at end of file
@@ -1,13 +1,16 @@
 package dbd
 
 import (
+	"fmt"
 	"log"
 	//"maps"
 	//"slices"
 	"context"
 	"regexp"
+	"database/sql"
 	"strconv"
 	"strings"
+	"math/big"
 )
 
 const (
@@ -15,6 +18,13 @@ const (
 	SCHEMA_INT 		= "int"
 	SCHEMA_DEC 		= "decimal"
 	SCHEMA_TEXT		= "text"
+	SCHEMA_FLOAT	= "float"
+	SCHEMA_DATE		= "date"
+	SCHEMA_BINARY	= "binary"
+	SCHEMA_BLOB		= "blob"
+	SCHEMA_JSON		= "json"
+	SCHEMA_BIT		= "bit"
+	SCHEMA_SPATIAL	= "spatial"
 	
 	TYPE_TINYINT 	= "tinyint"
 	TYPE_SMALLINT	= "smallint"
@@ -24,7 +34,8 @@ const (
 )
 
 var (
-	db_tables schema_tables
+	db_tables	schema_tables
+	db_indexes	schema_indexes
 	
 	integers = map[string]int{
 		TYPE_TINYINT:		int_pow(2, 8),
@@ -34,16 +45,24 @@ var (
 		TYPE_BIGINT:		int_pow(2, 64),
 	}
 	
-	schema_int 		= regexp.MustCompile(`^(`+TYPE_TINYINT+`|`+TYPE_SMALLINT+`|`+TYPE_MEDIUMINT+`|`+TYPE_INT+`|`+TYPE_BIGINT+`)\((\d+)\)(?: (.*))?`)
+	schema_int 		= regexp.MustCompile(`^(`+TYPE_TINYINT+`|`+TYPE_SMALLINT+`|`+TYPE_MEDIUMINT+`|`+TYPE_INT+`|`+TYPE_BIGINT+`)(?:\((\d+)\))?(?: (.*))?`)
 	schema_char 	= regexp.MustCompile(`^(varchar|char)\((\d+)\)`)
 	schema_decimal 	= regexp.MustCompile(`^(decimal)\((\d+),(\d+)\)(?: (.*))?`)
-	schema_enum 	= regexp.MustCompile(`^(enum)\((.*)\)`)
+	schema_enum 	= regexp.MustCompile(`^(enum|set)\((.*)\)`)
 	schema_text 	= regexp.MustCompile(`^(tinytext|text|mediumtext|longtext)$`)
+	schema_float 	= regexp.MustCompile(`^(float|double|real)(?:\((\d+),(\d+)\))?(?: (.*))?`)
+	schema_date 	= regexp.MustCompile(`^(date|datetime|timestamp|time|year)(?:\((\d+)\))?$`)
+	schema_binary 	= regexp.MustCompile(`^(binary|varbinary)\((\d+)\)`)
+	schema_blob 	= regexp.MustCompile(`^(tinyblob|blob|mediumblob|longblob)$`)
+	schema_json 	= regexp.MustCompile(`^(json)$`)
+	schema_bit 		= regexp.MustCompile(`^(bit)\((\d+)\)`)
+	schema_spatial 	= regexp.MustCompile(`^(geometry|point|linestring|polygon|multipoint|multilinestring|multipolygon|geometrycollection)$`)
 )
 
 type (
 	schema_tables	map[string]schema_table
 	schema_table 	map[string]schema_column
+	schema_indexes	map[string][]schema_index
 	
 	schema_column struct {
 		data_type		string
@@ -54,6 +73,17 @@ type (
 		null			bool
 		range_int 		length_range_int
 		range_dec 		length_range_dec
+		column_type		string		//	Raw column type (i.e. "int(10) unsigned")
+		def				*string
+		extra			string
+		position		int
+	}
+	
+	schema_index struct {
+		name			string
+		unique			bool
+		index_type		string
+		columns			[]string
 	}
 	
 	length_range_int struct {
@@ -65,10 +95,16 @@ type (
 		Min 	float64
 		Max		float64
 	}
+	
+	length_range_decimal struct {
+		Min 	Decimal
+		Max		Decimal
+	}
 )
 
 func Fetch_schema(){
-	db_tables = schema_tables{}
+	db_tables	= schema_tables{}
+	db_indexes	= schema_indexes{}
 	
 	rows, err := db.QueryContext(context.Background(), "SHOW TABLES")
 	if err != nil {
@@ -81,12 +117,30 @@ func Fetch_schema(){
 			log.Fatalf("Unable to fetch DB schema tables: %v", err)
 		}
 		fetch_schema_table(table)
+		fetch_schema_indexes(table)
 	}
 	if err := rows.Err(); err != nil {
 		log.Fatalf("Unable to fetch DB schema tables: %v", err)
 	}
 }
 
+//	Re-fetch the schema cache if it has been fetched (i.e. after migrations)
+func Refresh_schema(){
+	if db_tables != nil {
+		Fetch_schema()
+	}
+}
+
+func (s schema_tables) Exists_table(table string) bool {
+	_, found := s[table]
+	return found
+}
+
+func (s schema_tables) Exists_column(table, column string) bool {
+	_, found := s[table][column]
+	return found
+}
+
 func Exists_schema(table, column string) bool {
 	_, found := db_tables[table][column]
 	return found
@@ -134,6 +188,26 @@ func (s schema_column) Range_dec() length_range_dec {
 	return s.range_dec
 }
 
+//	Exact range of decimal columns
+func (s schema_column) Range_decimal() length_range_decimal {
+	l := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(s.length)), nil)
+	d := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(s.length_dec)), nil)
+	
+	max := Decimal{new(big.Rat).SetFrac(l.Sub(l, big.NewInt(1)), d)}
+	if s.unsigned {
+		return length_range_decimal{Decimal_int(0), max}
+	}
+	return length_range_decimal{Decimal{new(big.Rat).Neg(max.rat)}, max}
+}
+
+//	Check the value fits the precision and scale of the decimal column
+func (s schema_column) Validate_decimal(d Decimal) error {
+	if s.data_type != SCHEMA_DEC {
+		return fmt.Errorf("Column is not decimal: %s", s.column_type)
+	}
+	return d.Validate(s.length, s.length_dec, s.unsigned)
+}
+
 func fetch_schema_table(table string){
 	table_cols := schema_table{}
 	
@@ -142,6 +216,7 @@ func fetch_schema_table(table string){
 		log.Fatalf("Unable to fetch DB schema table: %v", err)
 	}
 	defer rows.Close()
+	var position int
 	for rows.Next() {
 		var (
 			column 	string
@@ -155,89 +230,225 @@ func fetch_schema_table(table string){
 			log.Fatalf("Unable to fetch DB schema table column: %v", err)
 		}
 		
-		var (
-			is_null			= null == "YES"
-			is_unsigned 	bool
-		)
-		
-		if matches := schema_int.FindStringSubmatch(format); len(matches) != 0 {
-			length, _		:= strconv.Atoi(matches[2])
-			is_unsigned 	= check_unsigned(matches[3])
-			
-			var (
-				min int
-				int_range = integers[matches[1]]
-			)
-			if !is_unsigned {
-				min = int_range / -2
-			}
-			
-			table_cols[column] = schema_column{
-				data_type:		SCHEMA_INT,
-				data_subtype:	matches[1],
-				length:			length,
-				unsigned:		is_unsigned,
-				null:			is_null,
-				range_int:		length_range_int{int64(min), int64(min + int_range - 1)},
-			}
-			continue
+		col_schema, ok := parse_schema_column(format, null == "YES")
+		if !ok {
+			log.Fatal("Unknown column: "+column+" "+format)
 		}
+		col_schema.column_type	= format
+		col_schema.def			= def
+		col_schema.extra		= extra
+		col_schema.position		= position
+		position++
 		
-		if matches := schema_char.FindStringSubmatch(format); len(matches) != 0 {
-			length, _ := strconv.Atoi(matches[2])
-			
-			table_cols[column] = schema_column{
-				data_type:		SCHEMA_CHAR,
-				data_subtype:	matches[1],
-				length:			length,
-				null:			is_null,
-			}
-			continue
+		table_cols[column] = col_schema
+	}
+	if err := rows.Err(); err != nil {
+		log.Fatalf("Unable to fetch DB schema table column: %v", err)
+	}
+	
+	db_tables[table] = table_cols
+}
+
+func fetch_schema_indexes(table string){
+	rows, err := db.QueryContext(context.Background(), "SHOW INDEX FROM ."+table)
+	if err != nil {
+		log.Fatalf("Unable to fetch DB schema table indexes: %v", err)
+	}
+	defer rows.Close()
+	
+	//	Number of columns varies between MySQL and MariaDB versions
+	cols, err := rows.Columns()
+	if err != nil {
+		log.Fatalf("Unable to fetch DB schema table indexes: %v", err)
+	}
+	values	:= make([]sql.NullString, len(cols))
+	scan	:= make([]any, len(cols))
+	pos		:= make(map[string]int, len(cols))
+	for i, col := range cols {
+		scan[i]		= &values[i]
+		pos[col]	= i
+	}
+	
+	var (
+		indexes	[]schema_index
+		lookup	= map[string]int{}
+	)
+	for rows.Next() {
+		if err := rows.Scan(scan...); err != nil {
+			log.Fatalf("Unable to fetch DB schema table index: %v", err)
 		}
 		
-		if matches := schema_decimal.FindStringSubmatch(format); len(matches) != 0 {
-			length, _		:= strconv.Atoi(matches[2])
-			dec, _			:= strconv.Atoi(matches[3])
-			is_unsigned 	= check_unsigned(matches[4])
-			min, max		:= decimal_range(length, dec, is_unsigned)
-			
-			table_cols[column] = schema_column{
-				data_type:		SCHEMA_DEC,
-				data_subtype:	matches[1],
-				length:			length,
-				length_dec:		dec,
-				unsigned:		is_unsigned,
-				null:			is_null,
-				range_dec:		length_range_dec{min, max},
-			}
-			continue
+		name	:= values[pos["Key_name"]].String
+		column	:= values[pos["Column_name"]].String
+		if i, ok := pos["Expression"]; ok && !values[pos["Column_name"]].Valid {
+			//	Functional key part (MySQL 8)
+			column = "("+values[i].String+")"
+		}
+		if sub_part := values[pos["Sub_part"]]; sub_part.Valid {
+			column += "("+sub_part.String+")"
 		}
 		
-		if matches := schema_enum.FindStringSubmatch(format); len(matches) != 0 {
-			table_cols[column] = schema_column{
-				data_type:		SCHEMA_CHAR,
-				data_subtype:	matches[1],
-				null:			is_null,
-			}
-			continue
+		i, found := lookup[name]
+		if !found {
+			i = len(indexes)
+			lookup[name] = i
+			indexes = append(indexes, schema_index{
+				name:		name,
+				unique:		values[pos["Non_unique"]].String == "0",
+				index_type:	values[pos["Index_type"]].String,
+			})
 		}
+		indexes[i].columns = append(indexes[i].columns, column)
+	}
+	if err := rows.Err(); err != nil {
+		log.Fatalf("Unable to fetch DB schema table index: %v", err)
+	}
+	
+	db_indexes[table] = indexes
+}
+
+func parse_schema_column(format string, is_null bool) (schema_column, bool){
+	var is_unsigned bool
+	
+	if matches := schema_int.FindStringSubmatch(format); len(matches) != 0 {
+		length, _		:= strconv.Atoi(matches[2])
+		is_unsigned 	= check_unsigned(matches[3])
 		
-		if matches := schema_text.FindStringSubmatch(format); len(matches) != 0 {
-			table_cols[column] = schema_column{
-				data_type:		SCHEMA_TEXT,
-				data_subtype:	matches[1],
-				null:			is_null,
-			}
-			continue
+		var (
+			min int
+			int_range = integers[matches[1]]
+		)
+		if !is_unsigned {
+			min = int_range / -2
 		}
 		
-		log.Fatal("Unknown column: "+column+" "+format)
+		return schema_column{
+			data_type:		SCHEMA_INT,
+			data_subtype:	matches[1],
+			length:			length,
+			unsigned:		is_unsigned,
+			null:			is_null,
+			range_int:		length_range_int{int64(min), int64(min + int_range - 1)},
+		}, true
 	}
-	if err := rows.Err(); err != nil {
-		log.Fatalf("Unable to fetch DB schema table column: %v", err)
+	
+	if matches := schema_char.FindStringSubmatch(format); len(matches) != 0 {
+		length, _ := strconv.Atoi(matches[2])
+		
+		return schema_column{
+			data_type:		SCHEMA_CHAR,
+			data_subtype:	matches[1],
+			length:			length,
+			null:			is_null,
+		}, true
 	}
 	
-	db_tables[table] = table_cols
+	if matches := schema_decimal.FindStringSubmatch(format); len(matches) != 0 {
+		length, _		:= strconv.Atoi(matches[2])
+		dec, _			:= strconv.Atoi(matches[3])
+		is_unsigned 	= check_unsigned(matches[4])
+		min, max		:= decimal_range(length, dec, is_unsigned)
+		
+		return schema_column{
+			data_type:		SCHEMA_DEC,
+			data_subtype:	matches[1],
+			length:			length,
+			length_dec:		dec,
+			unsigned:		is_unsigned,
+			null:			is_null,
+			range_dec:		length_range_dec{min, max},
+		}, true
+	}
+	
+	if matches := schema_enum.FindStringSubmatch(format); len(matches) != 0 {
+		return schema_column{
+			data_type:		SCHEMA_CHAR,
+			data_subtype:	matches[1],
+			null:			is_null,
+		}, true
+	}
+	
+	if matches := schema_text.FindStringSubmatch(format); len(matches) != 0 {
+		return schema_column{
+			data_type:		SCHEMA_TEXT,
+			data_subtype:	matches[1],
+			null:			is_null,
+		}, true
+	}
+	
+	if matches := schema_float.FindStringSubmatch(format); len(matches) != 0 {
+		length, _		:= strconv.Atoi(matches[2])
+		dec, _			:= strconv.Atoi(matches[3])
+		
+		return schema_column{
+			data_type:		SCHEMA_FLOAT,
+			data_subtype:	matches[1],
+			length:			length,
+			length_dec:		dec,
+			unsigned:		check_unsigned(matches[4]),
+			null:			is_null,
+		}, true
+	}
+	
+	if matches := schema_date.FindStringSubmatch(format); len(matches) != 0 {
+		//	Fractional seconds precision
+		dec, _ := strconv.Atoi(matches[2])
+		
+		return schema_column{
+			data_type:		SCHEMA_DATE,
+			data_subtype:	matches[1],
+			length_dec:		dec,
+			null:			is_null,
+		}, true
+	}
+	
+	if matches := schema_binary.FindStringSubmatch(format); len(matches) != 0 {
+		length, _ := strconv.Atoi(matches[2])
+		
+		return schema_column{
+			data_type:		SCHEMA_BINARY,
+			data_subtype:	matches[1],
+			length:			length,
+			null:			is_null,
+		}, true
+	}
+	
+	if matches := schema_blob.FindStringSubmatch(format); len(matches) != 0 {
+		return schema_column{
+			data_type:		SCHEMA_BLOB,
+			data_subtype:	matches[1],
+			null:			is_null,
+		}, true
+	}
+	
+	if matches := schema_json.FindStringSubmatch(format); len(matches) != 0 {
+		return schema_column{
+			data_type:		SCHEMA_JSON,
+			data_subtype:	matches[1],
+			null:			is_null,
+		}, true
+	}
+	
+	if matches := schema_bit.FindStringSubmatch(format); len(matches) != 0 {
+		length, _ := strconv.Atoi(matches[2])
+		
+		return schema_column{
+			data_type:		SCHEMA_BIT,
+			data_subtype:	matches[1],
+			length:			length,
+			null:			is_null,
+		}, true
+	}
+	
+	if matches := schema_spatial.FindStringSubmatch(format); len(matches) != 0 {
+		return schema_column{
+			data_type:		SCHEMA_SPATIAL,
+			data_subtype:	matches[1],
+			null:			is_null,
+		}, true
+	}
+	
+	return schema_column{}, false
 }
 
 func decimal_range(length int, dec int, unsigned bool) (float64, float64){
@@ -266,5 +477,6 @@ func int_pow(n, m int) int {
 }
 
 func check_unsigned(s string) bool {
-	return s == "unsigned"
+	//	"unsigned zerofill"
+	return strings.HasPrefix(s, "unsigned")
 }
\ No newline at end of file
@@ -0,0 +1,272 @@
+package dbd
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var default_raw = regexp.MustCompile(`(?i)^(?:'.*'|b'[01]*'|-?\d+(?:\.\d+)?(?:e[+-]?\d+)?|null|current_timestamp(?:\(\d*\))?|now\(\d*\)|curdate\(\)|uuid\(\)|\(.*\))$`)
+
+type (
+	Schema_diff struct {
+		Added_tables	[]string
+		Removed_tables	[]string
+		Changed_tables	[]Table_diff
+		to				*Snapshot
+	}
+	
+	Table_diff struct {
+		Table			string
+		Added_columns	[]Snapshot_column
+		Removed_columns	[]Snapshot_column
+		Changed_columns	[]Column_change
+		Added_indexes	[]Snapshot_index
+		Removed_indexes	[]Snapshot_index
+		Changed_indexes	[]Index_change
+		columns			[]Snapshot_column
+	}
+	
+	Column_change struct {
+		From			Snapshot_column
+		To				Snapshot_column
+	}
+	
+	Index_change struct {
+		From			Snapshot_index
+		To				Snapshot_index
+	}
+)
+
+//	Diff from the current state (i.e. live database) to the desired state
+func Diff_schema(from, to *Snapshot) *Schema_diff {
+	d := &Schema_diff{
+		to: to,
+	}
+	
+	for _, table := range to.Table_names() {
+		from_table, found := from.Tables[table]
+		if !found {
+			d.Added_tables = append(d.Added_tables, table)
+			continue
+		}
+		if td := diff_table(table, from_table, to.Tables[table]); !td.empty() {
+			d.Changed_tables = append(d.Changed_tables, td)
+		}
+	}
+	
+	for _, table := range from.Table_names() {
+		if _, found := to.Tables[table]; !found {
+			d.Removed_tables = append(d.Removed_tables, table)
+		}
+	}
+	return d
+}
+
+func (d *Schema_diff) Empty() bool {
+	return len(d.Added_tables) == 0 && len(d.Removed_tables) == 0 && len(d.Changed_tables) == 0
+}
+
+//	Ordered statements to converge the schema: CREATE TABLE, ALTER TABLE, DROP TABLE
+func (d *Schema_diff) Statements() []string {
+	list := make([]string, 0, len(d.Added_tables) + len(d.Changed_tables) + len(d.Removed_tables))
+	for _, table := range d.Added_tables {
+		list = append(list, create_table_sql(table, d.to.Tables[table]))
+	}
+	for i := range d.Changed_tables {
+		list = append(list, d.Changed_tables[i].alter_table_sql())
+	}
+	for _, table := range d.Removed_tables {
+		list = append(list, "DROP TABLE "+quote_identifier(table))
+	}
+	return list
+}
+
+func diff_table(table string, from, to *Snapshot_table) Table_diff {
+	td := Table_diff{
+		Table:		table,
+		columns:	to.Columns,
+	}
+	
+	for _, col := range to.Columns {
+		from_col, _, found := from.column(col.Name)
+		if !found {
+			td.Added_columns = append(td.Added_columns, col)
+			continue
+		}
+		if !equal_column(from_col, col) {
+			td.Changed_columns = append(td.Changed_columns, Column_change{from_col, col})
+		}
+	}
+	for _, col := range from.Columns {
+		if _, _, found := to.column(col.Name); !found {
+			td.Removed_columns = append(td.Removed_columns, col)
+		}
+	}
+	
+	for _, index := range to.Indexes {
+		from_index, found := from.index(index.Name)
+		if !found {
+			td.Added_indexes = append(td.Added_indexes, index)
+			continue
+		}
+		if !equal_index(from_index, index) {
+			td.Changed_indexes = append(td.Changed_indexes, Index_change{from_index, index})
+		}
+	}
+	for _, index := range from.Indexes {
+		if _, found := to.index(index.Name); !found {
+			td.Removed_indexes = append(td.Removed_indexes, index)
+		}
+	}
+	return td
+}
+
+func (td *Table_diff) empty() bool {
+	return len(td.Added_columns) == 0 && len(td.Removed_columns) == 0 && len(td.Changed_columns) == 0 &&
+		len(td.Added_indexes) == 0 && len(td.Removed_indexes) == 0 && len(td.Changed_indexes) == 0
+}
+
+func (td *Table_diff) alter_table_sql() string {
+	var clauses []string
+	
+	//	Drop indexes before columns they might depend on
+	for _, index := range td.Removed_indexes {
+		clauses = append(clauses, drop_index_sql(index))
+	}
+	for _, change := range td.Changed_indexes {
+		clauses = append(clauses, drop_index_sql(change.From))
+	}
+	for _, col := range td.Removed_columns {
+		clauses = append(clauses, "DROP COLUMN "+quote_identifier(col.Name))
+	}
+	for _, col := range td.Added_columns {
+		clause := "ADD COLUMN "+column_sql(col)
+		//	Keep column position
+		_, i, _ := (&Snapshot_table{Columns: td.columns}).column(col.Name)
+		if i == 0 {
+			clause += " FIRST"
+		} else {
+			clause += " AFTER "+quote_identifier(td.columns[i-1].Name)
+		}
+		clauses = append(clauses, clause)
+	}
+	for _, change := range td.Changed_columns {
+		clauses = append(clauses, "MODIFY COLUMN "+column_sql(change.To))
+	}
+	for _, index := range td.Added_indexes {
+		clauses = append(clauses, "ADD "+index_sql(index))
+	}
+	for _, change := range td.Changed_indexes {
+		clauses = append(clauses, "ADD "+index_sql(change.To))
+	}
+	
+	return "ALTER TABLE "+quote_identifier(td.Table)+"\n"+strings.Join(clauses, ",\n")
+}
+
+func create_table_sql(table string, t *Snapshot_table) string {
+	defs := make([]string, 0, len(t.Columns) + len(t.Indexes))
+	for _, col := range t.Columns {
+		defs = append(defs, column_sql(col))
+	}
+	for _, index := range t.Indexes {
+		defs = append(defs, index_sql(index))
+	}
+	return "CREATE TABLE "+quote_identifier(table)+" (\n"+strings.Join(defs, ",\n")+"\n)"
+}
+
+func column_sql(col Snapshot_column) string {
+	var sb strings.Builder
+	sb.WriteString(quote_identifier(col.Name))
+	sb.WriteByte(' ')
+	sb.WriteString(col.Type)
+	if col.Null {
+		sb.WriteString(" NULL")
+	} else {
+		sb.WriteString(" NOT NULL")
+	}
+	if col.Default != nil {
+		sb.WriteString(" DEFAULT ")
+		sb.WriteString(default_sql(*col.Default))
+	}
+	if extra := column_extra(col.Extra); extra != "" {
+		sb.WriteByte(' ')
+		sb.WriteString(extra)
+	}
+	return sb.String()
+}
+
+func index_sql(index Snapshot_index) string {
+	cols := make([]string, len(index.Columns))
+	for i, col := range index.Columns {
+		cols[i] = quote_index_column(col)
+	}
+	list := " ("+strings.Join(cols, ",")+")"
+	
+	switch {
+	case index.Name == "PRIMARY":
+		return "PRIMARY KEY"+list
+	case index.Type == "FULLTEXT":
+		return "FULLTEXT KEY "+quote_identifier(index.Name)+list
+	case index.Type == "SPATIAL":
+		return "SPATIAL KEY "+quote_identifier(index.Name)+list
+	case index.Unique:
+		return "UNIQUE KEY "+quote_identifier(index.Name)+list
+	default:
+		return "KEY "+quote_identifier(index.Name)+list
+	}
+}
+
+func drop_index_sql(index Snapshot_index) string {
+	if index.Name == "PRIMARY" {
+		return "DROP PRIMARY KEY"
+	}
+	return "DROP INDEX "+quote_identifier(index.Name)
+}
+
+func equal_column(a, b Snapshot_column) bool {
+	if a.Type != b.Type || a.Null != b.Null || column_extra(a.Extra) != column_extra(b.Extra) {
+		return false
+	}
+	if a.Default == nil || b.Default == nil {
+		return a.Default == b.Default
+	}
+	return *a.Default == *b.Default
+}
+
+func equal_index(a, b Snapshot_index) bool {
+	return a.Unique == b.Unique && a.Type == b.Type && slices.Equal(a.Columns, b.Columns)
+}
+
+//	Only keep attributes that can be part of a column definition
+func column_extra(extra string) string {
+	extra = strings.TrimSpace(strings.ReplaceAll(extra, "DEFAULT_GENERATED", ""))
+	if strings.HasSuffix(extra, "GENERATED") {
+		//	Generation expressions are not part of the snapshot
+		return ""
+	}
+	return extra
+}
+
+func default_sql(def string) string {
+	if default_raw.MatchString(def) {
+		return def
+	}
+	return "'"+strings.NewReplacer(`\`, `\\`, `'`, `''`).Replace(def)+"'"
+}
+
+//	Index column with optional prefix length, i.e. "name(10)"
+func quote_index_column(col string) string {
+	if strings.HasPrefix(col, "(") {
+		//	Functional key part
+		return col
+	}
+	if pos := strings.IndexByte(col, '('); pos != -1 {
+		return quote_identifier(col[:pos])+col[pos:]
+	}
+	return quote_identifier(col)
+}
+
+func quote_identifier(name string) string {
+	return "`"+strings.ReplaceAll(name, "`", "``")+"`"
+}
\ No newline at end of file
@@ -0,0 +1,158 @@
+package dbd
+
+/*
+	Test
+	# go test . -v
+*/
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+)
+
+func Test_schema_diff(t *testing.T){
+	t.Run("schema diff", func(t *testing.T){
+		zero := "0"
+		name := "it's"
+		
+		from := &Snapshot{
+			Tables: map[string]*Snapshot_table{
+				"user": {
+					Columns: []Snapshot_column{
+						{Name: "id", Type: "int(10) unsigned", Extra: "auto_increment"},
+						{Name: "name", Type: "varchar(100)"},
+						{Name: "legacy", Type: "tinyint(1)", Default: &zero},
+					},
+					Indexes: []Snapshot_index{
+						{Name: "PRIMARY", Unique: true, Type: "BTREE", Columns: []string{"id"}},
+						{Name: "name", Type: "BTREE", Columns: []string{"name"}},
+					},
+				},
+				"old": {
+					Columns: []Snapshot_column{
+						{Name: "id", Type: "int(10) unsigned"},
+					},
+				},
+			},
+		}
+		
+		to := &Snapshot{
+			Tables: map[string]*Snapshot_table{
+				"user": {
+					Columns: []Snapshot_column{
+						{Name: "id", Type: "int(10) unsigned", Extra: "auto_increment"},
+						{Name: "email", Type: "varchar(255)", Null: true},
+						{Name: "name", Type: "varchar(255)", Default: &name},
+					},
+					Indexes: []Snapshot_index{
+						{Name: "PRIMARY", Unique: true, Type: "BTREE", Columns: []string{"id"}},
+						{Name: "name", Unique: true, Type: "BTREE", Columns: []string{"name(10)"}},
+						{Name: "email", Type: "BTREE", Columns: []string{"email"}},
+					},
+				},
+				"client": {
+					Columns: []Snapshot_column{
+						{Name: "id", Type: "int(10) unsigned", Extra: "auto_increment"},
+						{Name: "time", Type: "timestamp", Default: ptr("current_timestamp()"), Extra: "on update current_timestamp()"},
+					},
+					Indexes: []Snapshot_index{
+						{Name: "PRIMARY", Unique: true, Type: "BTREE", Columns: []string{"id"}},
+					},
+				},
+			},
+		}
+		
+		d := Diff_schema(from, to)
+		
+		if !slices.Equal(d.Added_tables, []string{"client"}) {
+			t.Fatalf("Added tables got: %v", d.Added_tables)
+		}
+		if !slices.Equal(d.Removed_tables, []string{"old"}) {
+			t.Fatalf("Removed tables got: %v", d.Removed_tables)
+		}
+		if len(d.Changed_tables) != 1 {
+			t.Fatalf("Changed tables got: %v", d.Changed_tables)
+		}
+		
+		want := []string{
+"CREATE TABLE `client` (\n"+
+"`id` int(10) unsigned NOT NULL auto_increment,\n"+
+"`time` timestamp NOT NULL DEFAULT current_timestamp() on update current_timestamp(),\n"+
+"PRIMARY KEY (`id`)\n"+
+")",
+"ALTER TABLE `user`\n"+
+"DROP INDEX `name`,\n"+
+"DROP COLUMN `legacy`,\n"+
+"ADD COLUMN `email` varchar(255) NULL AFTER `id`,\n"+
+"MODIFY COLUMN `name` varchar(255) NOT NULL DEFAULT 'it''s',\n"+
+"ADD KEY `email` (`email`),\n"+
+"ADD UNIQUE KEY `name` (`name`(10))",
+"DROP TABLE `old`",
+		}
+		got := d.Statements()
+		if !slices.Equal(want, got) {
+			t.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+		}
+		
+		if !Diff_schema(to, to).Empty() {
+			t.Fatalf("Diff of same snapshot is not empty")
+		}
+	})
+	
+	t.Run("snapshot read write", func(t *testing.T){
+		s := &Snapshot{
+			Tables: map[string]*Snapshot_table{
+				"user": {
+					Columns: []Snapshot_column{
+						{Name: "id", Type: "int(10) unsigned"},
+					},
+				},
+			},
+		}
+		
+		var buf bytes.Buffer
+		if err := s.Write(&buf); err != nil {
+			t.Fatal(err)
+		}
+		got, err := Read_snapshot(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !Diff_schema(s, got).Empty() {
+			t.Fatalf("Snapshot changed after read/write")
+		}
+	})
+}
+
+func Test_parse_schema_column(t *testing.T){
+	for format, want := range map[string]string{
+		"int(10) unsigned":				SCHEMA_INT,
+		"bigint unsigned zerofill":		SCHEMA_INT,
+		"varchar(255)":					SCHEMA_CHAR,
+		"set('a','b')":					SCHEMA_CHAR,
+		"decimal(10,2)":				SCHEMA_DEC,
+		"double":						SCHEMA_FLOAT,
+		"datetime(3)":					SCHEMA_DATE,
+		"timestamp":					SCHEMA_DATE,
+		"varbinary(16)":				SCHEMA_BINARY,
+		"longblob":						SCHEMA_BLOB,
+		"json":							SCHEMA_JSON,
+		"bit(1)":						SCHEMA_BIT,
+		"point":						SCHEMA_SPATIAL,
+	}{
+		col_schema, ok := parse_schema_column(format, false)
+		if !ok || col_schema.data_type != want {
+			t.Fatalf("Column type %s want: %s got: %s", format, want, col_schema.data_type)
+		}
+	}
+	
+	col_schema, _ := parse_schema_column("bigint unsigned zerofill", false)
+	if !col_schema.unsigned {
+		t.Fatalf("Column type bigint unsigned zerofill must be unsigned")
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
\ No newline at end of file
@@ -0,0 +1,132 @@
+package dbd
+
+import (
+	"io"
+	"fmt"
+	"slices"
+	"encoding/json"
+)
+
+type (
+	Snapshot struct {
+		Tables		map[string]*Snapshot_table	`json:"tables"`
+	}
+	
+	Snapshot_table struct {
+		Columns		[]Snapshot_column			`json:"columns"`
+		Indexes		[]Snapshot_index			`json:"indexes,omitempty"`
+	}
+	
+	Snapshot_column struct {
+		Name		string						`json:"name"`
+		Type		string						`json:"type"`
+		Null		bool						`json:"null,omitempty"`
+		Default		*string						`json:"default,omitempty"`
+		Extra		string						`json:"extra,omitempty"`
+	}
+	
+	Snapshot_index struct {
+		Name		string						`json:"name"`
+		Unique		bool						`json:"unique,omitempty"`
+		Type		string						`json:"type,omitempty"`
+		Columns		[]string					`json:"columns"`
+	}
+)
+
+//	Snapshot of the fetched schema (Fetch_schema)
+func Schema_snapshot() *Snapshot {
+	if db_tables == nil {
+		panic("Unable to snapshot schema: Schema is not fetched")
+	}
+	
+	s := &Snapshot{
+		Tables: make(map[string]*Snapshot_table, len(db_tables)),
+	}
+	for table, table_cols := range db_tables {
+		t := &Snapshot_table{
+			Columns: make([]Snapshot_column, len(table_cols)),
+		}
+		for column, col_schema := range table_cols {
+			t.Columns[col_schema.position] = Snapshot_column{
+				Name:		column,
+				Type:		col_schema.column_type,
+				Null:		col_schema.null,
+				Default:	col_schema.def,
+				Extra:		col_schema.extra,
+			}
+		}
+		for _, index := range db_indexes[table] {
+			t.Indexes = append(t.Indexes, Snapshot_index{
+				Name:		index.name,
+				Unique:		index.unique,
+				Type:		index.index_type,
+				Columns:	index.columns,
+			})
+		}
+		s.Tables[table] = t
+	}
+	return s
+}
+
+func Read_snapshot(r io.Reader) (*Snapshot, error){
+	s := &Snapshot{}
+	if err := json.NewDecoder(r).Decode(s); err != nil {
+		return nil, fmt.Errorf("Unable to read schema snapshot: %w", err)
+	}
+	if s.Tables == nil {
+		s.Tables = map[string]*Snapshot_table{}
+	}
+	return s, nil
+}
+
+func (s *Snapshot) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	if err := enc.Encode(s); err != nil {
+		return fmt.Errorf("Unable to write schema snapshot: %w", err)
+	}
+	return nil
+}
+
+//	Implements sqlc.Schema
+func (s *Snapshot) Exists_table(table string) bool {
+	_, found := s.Tables[table]
+	return found
+}
+
+func (s *Snapshot) Exists_column(table, column string) bool {
+	t, found := s.Tables[table]
+	if !found {
+		return false
+	}
+	_, _, found = t.column(column)
+	return found
+}
+
+//	Sorted table names
+func (s *Snapshot) Table_names() []string {
+	keys := make([]string, 0, len(s.Tables))
+	for table := range s.Tables {
+		keys = append(keys, table)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func (t *Snapshot_table) column(name string) (Snapshot_column, int, bool){
+	for i, col := range t.Columns {
+		if col.Name == name {
+			return col, i, true
+		}
+	}
+	return Snapshot_column{}, -1, false
+}
+
+func (t *Snapshot_table) index(name string) (Snapshot_index, bool){
+	for _, index := range t.Indexes {
+		if index.Name == name {
+			return index, true
+		}
+	}
+	return Snapshot_index{}, false
+}
\ No newline at end of file
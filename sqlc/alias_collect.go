@@ -16,6 +16,14 @@ var alias_collect_pool = sync.Pool{
 type alias_collect map[string]struct{}
 
 func (m alias_collect) apply(field string){
+	//	Strip function in "func|field" (HAVING)
+	if pos := strings.IndexByte(field, '|'); pos != -1 {
+		if field[:pos] == SELECT_RAW {
+			m.apply_raw(field[pos+1:])
+			return
+		}
+		field = field[pos+1:]
+	}
 	if pos := strings.IndexByte(field, '.'); pos != -1 {
 		alias := field[:pos]
 		if alias == ROOT_ALIAS {
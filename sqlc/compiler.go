@@ -7,6 +7,7 @@ type compiler struct {
 	use_alias	bool
 	root_t 		string
 	tables		map[string]string
+	ctes		map[string]struct{}
 	data		[]any
 }
 
@@ -17,11 +18,14 @@ func (c *compiler) reset(){
 	if c.tables != nil {
 		clear(c.tables)
 	}
+	if c.ctes != nil {
+		clear(c.ctes)
+	}
 	c.data = c.data[:0]
 }
 
 func (c *compiler) write_field(t, field string){
-	if !c.use_alias {
+	if !c.use_alias || field == "*" {
 		c.sb.WriteString(field)
 		return
 	}
@@ -41,6 +45,32 @@ func (c *compiler) write_field(t, field string){
 	c.sb.WriteString(field)
 }
 
+//	CTE names are written without the database prefix "."
+func (c *compiler) write_table(table string){
+	if _, ok := c.ctes[table]; !ok {
+		c.sb.WriteByte('.')
+	}
+	c.sb.WriteString(table)
+}
+
+func (c *compiler) add_cte(name string){
+	if c.ctes == nil {
+		c.ctes = make(map[string]struct{}, 2)
+	}
+	c.ctes[name] = struct{}{}
+}
+
+//	Table alias is used by another table or a CTE name
+func (c *compiler) alias_used(alias, table string) bool {
+	if _, ok := c.tables[alias]; ok {
+		return true
+	}
+	if _, ok := c.ctes[alias]; ok && alias != table {
+		return true
+	}
+	return false
+}
+
 func (c *compiler) append_data(val any){
 	//	Flatten data slices
 	switch v := val.(type) {
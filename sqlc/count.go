@@ -0,0 +1,114 @@
+package sqlc
+
+import "slices"
+
+type Count_query struct {
+	query		SQL
+	derived		bool	//	Count rows of a derived table (DISTINCT, GROUP BY and UNION)
+}
+
+//	Count rows with the same FROM/JOIN/WHERE (without ORDER, LIMIT and select json)
+func (q *Select_query) Count_query() *Count_query {
+	c := q.count_copy()
+	
+	switch {
+	case q.select_distinct:
+		c.select_fields		= q.select_fields
+		c.select_distinct	= true
+		c.group				= q.group
+		c.having			= q.having
+	case q.having != nil:
+		//	Having conditions can use select aliases
+		c.select_fields		= q.select_fields
+		c.group				= q.group
+		c.having			= q.having
+	case len(q.group) != 0:
+		c.select_fields		= []select_field{{field: "1", function: SELECT_RAW}}
+		c.group				= q.group
+	default:
+		c.select_fields		= []select_field{{field: "COUNT(*)", function: SELECT_RAW}}
+		c.count_joins(q)
+		return &Count_query{
+			query:	c,
+		}
+	}
+	c.count_joins(q)
+	return &Count_query{
+		query:		c,
+		derived:	true,
+	}
+}
+
+func (q *Union_query) Count_query() *Count_query {
+	c := &Union_query{
+		Select_query:	*q.Select_query.count_copy(),
+		members:		q.members,
+		all:			q.all,
+	}
+	c.select_fields		= q.select_fields
+	c.select_distinct	= q.select_distinct
+	c.group				= q.group
+	c.having			= q.having
+	return &Count_query{
+		query:		c,
+		derived:	true,
+	}
+}
+
+func (q *Count_query) Compile() (string, []any, error){
+	sql, data, err := q.query.Compile()
+	if err != nil {
+		return "", nil, err
+	}
+	if !q.derived {
+		return sql, data, nil
+	}
+	
+	ctx := compiler_pool.Get().(*compiler)
+	defer func() {
+		ctx.reset()
+		compiler_pool.Put(ctx)
+	}()
+	
+	//	Pre-allocation
+	ctx.sb.Alloc(27 + len(sql) + 4)	//	"SELECT COUNT(*)\nFROM (\n" + ") t\n"
+	
+	ctx.sb.WriteString("SELECT COUNT(*)\nFROM (\n")
+	ctx.sb.WriteString(sql)
+	ctx.sb.WriteString(") t\n")
+	return ctx.sb.String(), data, nil
+}
+
+func (q *Count_query) Validate(schema Schema) error {
+	if v, ok := q.query.(Validator); ok {
+		return v.Validate(schema)
+	}
+	return nil
+}
+
+//	Same FROM/JOIN/WHERE (joins are copied as optimize joins changes their depth)
+func (q *Select_query) count_copy() *Select_query {
+	c := &Select_query{
+		query_where:	q.query_where,
+	}
+	c.joins = slices.Clone(q.joins)
+	return c
+}
+
+//	Joins of the original query are kept even if only used in SELECT or ORDER (inner joins filter rows and 1:N joins add rows)
+func (c *Select_query) count_joins(q *Select_query){
+	if !q.joined || !q.optimize_joins {
+		return
+	}
+	
+	//	Collect on a copy as join depths are changed
+	original		:= *q
+	original.joins	= slices.Clone(q.joins)
+	list			:= alias_collect{}
+	//	Errors are returned when the query is compiled
+	if original.collect_aliases(list) != nil {
+		return
+	}
+	c.joins				= original.compile_optimize_joins(list)
+	c.optimize_joins	= false
+}
\ No newline at end of file
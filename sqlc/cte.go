@@ -0,0 +1,134 @@
+package sqlc
+
+import "fmt"
+
+type (
+	Cte struct {
+		name			string
+		query			*Select_query
+		recursive		*Select_query	//	Recursive member (UNION ALL)
+	}
+	
+	cte_schema struct {
+		Schema
+		columns			map[string]map[string]struct{}	//	CTE name => output columns (nil: any column)
+	}
+)
+
+//	Common table expression: "WITH name AS (...)"
+func With(name string, query *Select_query) *Cte {
+	return &Cte{
+		name:	name,
+		query:	query,
+	}
+}
+
+//	Recursive common table expression: "WITH RECURSIVE name AS (anchor UNION ALL recursive)"
+func With_recursive(name string, anchor, recursive *Select_query) *Cte {
+	return &Cte{
+		name:		name,
+		query:		anchor,
+		recursive:	recursive,
+	}
+}
+
+func (q *query_join) with(ctes []*Cte){
+	q.ctes = append(q.ctes, ctes...)
+}
+
+func (q *query_join) compile_with(ctx *compiler) error {
+	if len(q.ctes) == 0 {
+		return nil
+	}
+	
+	//	CTE names are known tables in all members and the main query
+	var recursive bool
+	for i, c := range q.ctes {
+		if c.name == "" || c.query == nil {
+			return fmt.Errorf("CTE must have a name and a query")
+		}
+		for _, prev := range q.ctes[:i] {
+			if prev.name == c.name {
+				return fmt.Errorf("Duplicate CTE: %s", c.name)
+			}
+		}
+		ctx.add_cte(c.name)
+		if c.recursive != nil {
+			recursive = true
+		}
+	}
+	
+	//	Pre-allocation
+	ctx.sb.Alloc(15 + len(q.ctes) * (alloc_query + 8))	//	"WITH RECURSIVE \n" + " AS (\n" + ", "
+	
+	if recursive {
+		ctx.sb.WriteString("WITH RECURSIVE ")
+	} else {
+		ctx.sb.WriteString("WITH ")
+	}
+	for i, c := range q.ctes {
+		if i > 0 {
+			ctx.sb.WriteString(", ")
+		}
+		ctx.sb.WriteString(c.name)
+		ctx.sb.WriteString(" AS (\n")
+		if err := compile_subquery(ctx, c.query); err != nil {
+			return err
+		}
+		if c.recursive != nil {
+			ctx.sb.WriteString("UNION ALL\n")
+			if err := compile_subquery(ctx, c.recursive); err != nil {
+				return err
+			}
+		}
+		ctx.sb.WriteByte(')')
+	}
+	ctx.sb.WriteByte('\n')
+	return nil
+}
+
+//	CTE names are known tables with the output columns of the (anchor) query
+func (q *query_join) validate_with(schema Schema, errs *validate_errors) Schema {
+	if len(q.ctes) == 0 {
+		return schema
+	}
+	
+	s := &cte_schema{
+		Schema:		schema,
+		columns:	make(map[string]map[string]struct{}, len(q.ctes)),
+	}
+	for _, c := range q.ctes {
+		if c.query == nil {
+			continue
+		}
+		s.columns[c.name] = select_output_columns(c.query)
+	}
+	for _, c := range q.ctes {
+		if c.query == nil {
+			continue
+		}
+		c.query.validate(s, errs, "")
+		if c.recursive != nil {
+			c.recursive.validate(s, errs, "")
+		}
+	}
+	return s
+}
+
+func (s *cte_schema) Exists_table(table string) bool {
+	if _, ok := s.columns[table]; ok {
+		return true
+	}
+	return s.Schema.Exists_table(table)
+}
+
+func (s *cte_schema) Exists_column(table, column string) bool {
+	if columns, ok := s.columns[table]; ok {
+		if columns == nil {
+			return true
+		}
+		_, ok = columns[column]
+		return ok
+	}
+	return s.Schema.Exists_column(table, column)
+}
\ No newline at end of file
@@ -30,6 +30,11 @@ func (q *Delete_query) Left_join(table, t, field, field_foreign string) *Delete_
 	return q
 }
 
+func (q *Delete_query) With(ctes ...*Cte) *Delete_query {
+	q.with(ctes)
+	return q
+}
+
 func (q *Delete_query) Where(clause *Where_clause) *Delete_query {
 	q.where_clause = clause
 	return q
@@ -46,10 +51,14 @@ func (q *Delete_query) Compile() (string, []any, error){
 		compiler_pool.Put(ctx)
 	}()
 	
-	if q.joined {
+	//	Index hints require the multi-table syntax
+	if q.joined || q.where_clause.correlated() || q.base_index_hints() {
 		ctx.use_alias = true
 	}
 	
+	if err := q.compile_with(ctx); err != nil {
+		return "", nil, err
+	}
 	t := q.base_table_short()
 	if err := q.compile_tables(ctx, t); err != nil {
 		return "", nil, err
@@ -65,6 +74,7 @@ func (q *Delete_query) Compile() (string, []any, error){
 	
 	var err error
 	ctx.sb.WriteString("DELETE ")
+	q.write_optimizer_hints(ctx)
 	if ctx.use_alias {
 		ctx.sb.WriteString(q.t)
 		ctx.sb.WriteByte(' ')
@@ -1,6 +1,9 @@
 package sqlc
 
-const op_update_add = "+"
+const (
+	op_update_add	= "+"
+	op_update_json	= "json"	//	JSON path edits
+)
 
 type (
 	Fields_clause struct {
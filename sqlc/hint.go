@@ -0,0 +1,188 @@
+package sqlc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	Index_use		Index_hint = "USE INDEX"
+	Index_force		Index_hint = "FORCE INDEX"
+	Index_ignore	Index_hint = "IGNORE INDEX"
+)
+
+type (
+	Index_hint			string
+	
+	index_hint struct {
+		t				string	//	Join table alias (empty: base table)
+		hint			Index_hint
+		indexes			[]string
+	}
+)
+
+//	Index hint on the base table: "FROM .user u USE INDEX (idx)"
+func (q *Select_query) Use_index(indexes ...string) *Select_query {
+	q.index_hint("", Index_use, indexes)
+	return q
+}
+
+func (q *Select_query) Force_index(indexes ...string) *Select_query {
+	q.index_hint("", Index_force, indexes)
+	return q
+}
+
+func (q *Select_query) Ignore_index(indexes ...string) *Select_query {
+	q.index_hint("", Index_ignore, indexes)
+	return q
+}
+
+//	Index hint on the joined table with alias t: "LEFT JOIN .client c USE INDEX (idx) ON ..."
+func (q *Select_query) Join_index(t string, hint Index_hint, indexes ...string) *Select_query {
+	q.index_hint(t, hint, indexes)
+	return q
+}
+
+//	Optimizer hint comment: "SELECT /*+ BKA(u) */ ..."
+func (q *Select_query) Optimizer_hint(hint string) *Select_query {
+	q.optimizer_hints = append(q.optimizer_hints, hint)
+	return q
+}
+
+//	Abort the query after ms milliseconds: "SELECT /*+ MAX_EXECUTION_TIME(1000) */ ..."
+func (q *Select_query) Max_execution_time(ms uint32) *Select_query {
+	q.optimizer_hints = append(q.optimizer_hints, "MAX_EXECUTION_TIME("+strconv.FormatUint(uint64(ms), 10)+")")
+	return q
+}
+
+//	Join tables in the order they are listed: "SELECT STRAIGHT_JOIN ..."
+func (q *Select_query) Straight_join() *Select_query {
+	q.straight_join = true
+	return q
+}
+
+func (q *Update_query) Use_index(indexes ...string) *Update_query {
+	q.index_hint("", Index_use, indexes)
+	return q
+}
+
+func (q *Update_query) Force_index(indexes ...string) *Update_query {
+	q.index_hint("", Index_force, indexes)
+	return q
+}
+
+func (q *Update_query) Ignore_index(indexes ...string) *Update_query {
+	q.index_hint("", Index_ignore, indexes)
+	return q
+}
+
+func (q *Update_query) Join_index(t string, hint Index_hint, indexes ...string) *Update_query {
+	q.index_hint(t, hint, indexes)
+	return q
+}
+
+func (q *Update_query) Optimizer_hint(hint string) *Update_query {
+	q.optimizer_hints = append(q.optimizer_hints, hint)
+	return q
+}
+
+//	Index hints require the multi-table syntax: "DELETE u FROM .user u USE INDEX (idx)"
+func (q *Delete_query) Use_index(indexes ...string) *Delete_query {
+	q.index_hint("", Index_use, indexes)
+	return q
+}
+
+func (q *Delete_query) Force_index(indexes ...string) *Delete_query {
+	q.index_hint("", Index_force, indexes)
+	return q
+}
+
+func (q *Delete_query) Ignore_index(indexes ...string) *Delete_query {
+	q.index_hint("", Index_ignore, indexes)
+	return q
+}
+
+func (q *Delete_query) Join_index(t string, hint Index_hint, indexes ...string) *Delete_query {
+	q.index_hint(t, hint, indexes)
+	return q
+}
+
+func (q *Delete_query) Optimizer_hint(hint string) *Delete_query {
+	q.optimizer_hints = append(q.optimizer_hints, hint)
+	return q
+}
+
+func (q *query_join) index_hint(t string, hint Index_hint, indexes []string){
+	q.index_hints = append(q.index_hints, index_hint{t, hint, indexes})
+}
+
+//	Base table has index hints
+func (q *query_join) base_index_hints() bool {
+	for _, h := range q.index_hints {
+		if h.t == "" {
+			return true
+		}
+	}
+	return false
+}
+
+func (q *query_join) hint_error() error {
+	for _, h := range q.index_hints {
+		if len(h.indexes) == 0 {
+			return fmt.Errorf("Index hint without indexes: %s", h.hint)
+		}
+		if h.t == "" {
+			continue
+		}
+		j := q.join_by_alias(h.t)
+		if j == nil {
+			return fmt.Errorf("Unknown join table alias for index hint: %s", h.t)
+		}
+		if j.query != nil {
+			return fmt.Errorf("Index hint on join subquery: %s", h.t)
+		}
+	}
+	for _, hint := range q.optimizer_hints {
+		if strings.Contains(hint, "*/") {
+			return fmt.Errorf("Invalid optimizer hint: %s", hint)
+		}
+	}
+	return nil
+}
+
+func (q *query_join) join_by_alias(t string) *join {
+	for i := range q.joins {
+		if q.joins[i].t == t {
+			return &q.joins[i]
+		}
+	}
+	return nil
+}
+
+//	Index hints on the table with alias t (empty: base table)
+func (q *query_join) write_index_hints(ctx *compiler, t string){
+	for _, h := range q.index_hints {
+		if h.t != t {
+			continue
+		}
+		ctx.sb.WriteByte(' ')
+		ctx.sb.WriteString(string(h.hint))
+		ctx.sb.WriteString(" (")
+		ctx.sb.WriteString(strings.Join(h.indexes, ", "))
+		ctx.sb.WriteByte(')')
+	}
+}
+
+//	Optimizer hint comment followed by a space: "/*+ a b */ "
+func (q *query_join) write_optimizer_hints(ctx *compiler){
+	if len(q.optimizer_hints) == 0 {
+		return
+	}
+	ctx.sb.WriteString("/*+ ")
+	for _, hint := range q.optimizer_hints {
+		ctx.sb.WriteString(hint)
+		ctx.sb.WriteByte(' ')
+	}
+	ctx.sb.WriteString("*/ ")
+}
\ No newline at end of file
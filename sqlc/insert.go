@@ -115,6 +115,9 @@ func (q *Insert_query) compile_fields(ctx *compiler) error {
 		if _, found := unique[entry.field]; found {
 			return fmt.Errorf("Duplicate field: %s", entry.field)
 		}
+		if entry.operator == op_update_json {
+			return fmt.Errorf("JSON path edits are only valid in updates: %s", entry.field)
+		}
 		if i > 0 {
 			ctx.sb.WriteString(", ")
 		}
@@ -145,9 +148,9 @@ func (q *Insert_query) compile_update_duplicate_fields(ctx *compiler) error {
 				ctx.sb.WriteString(", ")
 			}
 			
-			q.write_update_field(ctx, field, q.fields.entries[j].operator)
-			
-			ctx.append_data(q.fields.entries[j].value)
+			if err := q.write_update_field(ctx, field, q.fields.entries[j].operator, q.fields.entries[j].value); err != nil {
+				return err
+			}
 		}
 	} else {
 		length := len(q.fields.entries)
@@ -159,9 +162,9 @@ func (q *Insert_query) compile_update_duplicate_fields(ctx *compiler) error {
 				ctx.sb.WriteString(", ")
 			}
 			
-			q.write_update_field(ctx, entry.field, entry.operator)
-			
-			ctx.append_data(entry.value)
+			if err := q.write_update_field(ctx, entry.field, entry.operator, entry.value); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
@@ -0,0 +1,238 @@
+package sqlc
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	json_array json_mode = iota
+	json_object
+	json_objectagg
+)
+
+type (
+	json_mode			uint8
+	
+	//	JSON document of a subquery as a select field
+	Json_agg struct {
+		query			*Select_query
+		mode			json_mode
+		key				string		//	Key field (JSON_OBJECTAGG)
+		order			[]string	//	Order inside the aggregate
+		empty			bool		//	Empty result is "[]" or "{}" instead of NULL
+		inner_field		string
+		outer_field		string
+	}
+)
+
+//	Array of objects: "JSON_ARRAYAGG(JSON_OBJECT(...))"
+func Json_array(query *Select_query) *Json_agg {
+	return &Json_agg{
+		query:	query,
+	}
+}
+
+//	Single object of the first row (to-one relations): "JSON_OBJECT(...) ... LIMIT 1"
+func Json_object(query *Select_query) *Json_agg {
+	return &Json_agg{
+		query:	query,
+		mode:	json_object,
+	}
+}
+
+//	Object of objects with the key field as keys: "JSON_OBJECTAGG(key, JSON_OBJECT(...))"
+func Json_objectagg(key string, query *Select_query) *Json_agg {
+	return &Json_agg{
+		query:	query,
+		mode:	json_objectagg,
+		key:	key,
+	}
+}
+
+//	Correlated subquery: Inner field (subquery) equals outer field
+func (j *Json_agg) Condition(inner_field, outer_field string) *Json_agg {
+	j.inner_field = inner_field
+	j.outer_field = outer_field
+	return j
+}
+
+//	Order of the rows in the document: "JSON_ARRAYAGG(... ORDER BY x)" (MariaDB)
+func (j *Json_agg) Order(fields ...string) *Json_agg {
+	j.order = fields
+	return j
+}
+
+//	Empty result is "[]" (or "{}" with objects) instead of NULL
+func (j *Json_agg) Empty() *Json_agg {
+	j.empty = true
+	return j
+}
+
+func (q *Select_query) Select_json_agg(field string, j *Json_agg) *Select_query {
+	q.select_jsons = append(q.select_jsons, &select_json{
+		select_field:	field,
+		Json_agg:		*j,
+	})
+	return q
+}
+
+//	JSON subquery with q as the outer query
+func (q *Select_query) write_json_subquery(ctx *compiler, sj *select_json) error {
+	if sj.query == nil {
+		ctx.sb.WriteString("NULL")
+		return nil
+	}
+	sub := sj.query
+	if len(sub.select_fields) == 0 && len(sub.select_jsons) == 0 {
+		return fmt.Errorf("Select json without fields: %s", sj.select_field)
+	}
+	if sj.mode == json_objectagg && len(sj.order) != 0 {
+		return fmt.Errorf("Select json order is not supported with JSON_OBJECTAGG: %s", sj.select_field)
+	}
+	
+	var sub_aliases alias_collect
+	
+	if sub.joined && sub.optimize_joins {
+		sub_aliases = alias_collect_pool.Get().(alias_collect)
+		defer func() {
+			sub_aliases.reset()
+			alias_collect_pool.Put(sub_aliases)
+		}()
+		if err := sub.collect_aliases(sub_aliases); err != nil {
+			return err
+		}
+		sub_aliases.apply(sj.key)
+		for _, f := range sj.order {
+			sub_aliases.apply(f)
+		}
+	}
+	
+	t := sub.base_table_short()
+	if err := sub.compile_tables(ctx, t); err != nil {
+		return err
+	}
+	
+	if sj.empty {
+		ctx.sb.WriteString("COALESCE(")
+	}
+	ctx.sb.WriteString("(\nSELECT ")
+	switch sj.mode {
+	case json_array:
+		ctx.sb.WriteString("JSON_ARRAYAGG(")
+	case json_objectagg:
+		ctx.sb.WriteString("JSON_OBJECTAGG(")
+		ctx.write_field(sub.t, sj.key)
+		ctx.sb.WriteString(", ")
+	}
+	if err := sub.write_json_object(ctx); err != nil {
+		return err
+	}
+	if sj.mode == json_array {
+		if len(sj.order) != 0 {
+			ctx.sb.WriteString(" ORDER BY ")
+			sj.write_order(ctx)
+		}
+		ctx.sb.WriteByte(')')
+	} else if sj.mode == json_objectagg {
+		ctx.sb.WriteByte(')')
+	}
+	ctx.sb.WriteByte('\n')
+	
+	sub.compile_from(ctx)
+	if err := sub.compile_joins(ctx, sub_aliases); err != nil {
+		return err
+	}
+	
+	if err := sub.compile_where(ctx, func(ctx *compiler, first *bool){
+		if sj.inner_field == "" {
+			return
+		}
+		
+		if *first {
+			*first = false
+		} else {
+			ctx.sb.WriteString(" AND ")
+		}
+		
+		ctx.write_field(sub.t, sj.inner_field)
+		ctx.sb.WriteByte('=')
+		ctx.write_field(q.t, sj.outer_field)
+	}); err != nil {
+		return err
+	}
+	
+	sub.compile_group(ctx)
+	if sj.mode == json_object {
+		if len(sj.order) != 0 {
+			ctx.sb.WriteString("ORDER BY ")
+			sj.write_order(ctx)
+			ctx.sb.WriteByte('\n')
+		} else {
+			sub.compile_order(ctx)
+		}
+		ctx.sb.WriteString("LIMIT 1\n")
+	} else {
+		sub.compile_order(ctx)
+		sub.compile_limit(ctx)
+	}
+	ctx.sb.WriteByte(')')
+	
+	if sj.empty {
+		if sj.mode == json_array {
+			ctx.sb.WriteString(", JSON_ARRAY())")
+		} else {
+			ctx.sb.WriteString(", JSON_OBJECT())")
+		}
+	}
+	return nil
+}
+
+//	Select fields and nested JSON subqueries as keys: "JSON_OBJECT('key', a.key, 'nested', (...))"
+func (q *Select_query) write_json_object(ctx *compiler) error {
+	ctx.sb.WriteString("JSON_OBJECT(")
+	for i := range q.select_fields {
+		field := &q.select_fields[i]	//	Avoid copying data
+		if i > 0 {
+			ctx.sb.WriteString(", ")
+		}
+		ctx.sb.WriteByte('\'')
+		ctx.sb.WriteString(json_key(field))
+		ctx.sb.WriteString("', ")
+		if err := q.write_select_field(ctx, field); err != nil {
+			return err
+		}
+	}
+	for i, sj := range q.select_jsons {
+		if i > 0 || len(q.select_fields) != 0 {
+			ctx.sb.WriteString(", ")
+		}
+		ctx.sb.WriteByte('\'')
+		ctx.sb.WriteString(sj.select_field)
+		ctx.sb.WriteString("', ")
+		if err := q.write_json_subquery(ctx, sj); err != nil {
+			return err
+		}
+	}
+	ctx.sb.WriteByte(')')
+	return nil
+}
+
+func (sj *select_json) write_order(ctx *compiler){
+	for i, f := range sj.order {
+		if i > 0 {
+			ctx.sb.WriteString(", ")
+		}
+		sj.query.write_order_field(ctx, f)
+	}
+}
+
+func json_key(field *select_field) string {
+	if field.alias != "" {
+		return field.alias
+	}
+	if pos := strings.IndexByte(field.field, '.'); pos != -1 {
+		return field.field[pos+1:]
+	}
+	return field.field
+}
\ No newline at end of file
@@ -0,0 +1,307 @@
+package sqlc
+
+import (
+	"fmt"
+	"strings"
+	"encoding/json"
+)
+
+const (
+	json_eq				= "eq"
+	json_contains		= "contains"
+	json_overlaps		= "overlaps"
+	
+	op_json_set			= "JSON_SET"
+	op_json_remove		= "JSON_REMOVE"
+	op_json_array_append	= "JSON_ARRAY_APPEND"
+)
+
+type (
+	//	Value at a JSON path as a select field
+	json_extract struct {
+		field			string
+		path			string
+		unquote			bool
+	}
+	
+	json_condition struct {
+		function		string
+		field			string
+		path			string
+		value			any
+	}
+	
+	//	Path edits of a JSON column (UPDATE)
+	json_edits struct {
+		list			[]json_edit
+	}
+	
+	json_edit struct {
+		function		string
+		path			string
+		value			any
+	}
+)
+
+//	JSON value at the path: "JSON_EXTRACT(x, '$.a') alias"
+func (q *Select_query) Select_json_extract(alias, field, path string) *Select_query {
+	q.select_fields = append(q.select_fields, select_field{
+		alias:		alias,
+		extract:	&json_extract{field, path, false},
+	})
+	return q
+}
+
+//	Unquoted value at the path (x->>'$.a'): "JSON_UNQUOTE(JSON_EXTRACT(x, '$.a')) alias"
+func (q *Select_query) Select_json_value(alias, field, path string) *Select_query {
+	q.select_fields = append(q.select_fields, select_field{
+		alias:		alias,
+		extract:	&json_extract{field, path, true},
+	})
+	return q
+}
+
+//	Value at the path equals value: Strings are compared unquoted "JSON_UNQUOTE(JSON_EXTRACT(x, '$.a'))=?" and other values as JSON "JSON_EXTRACT(x, '$.a')=?"
+func (w *Where_clause) Json_eq(field, path string, value any) *Where_clause {
+	w.clause("", Op_json, &json_condition{json_eq, field, path, value})
+	return w
+}
+
+//	Document at the path contains value (encoded as JSON): "JSON_CONTAINS(x, ?, '$.a')"
+func (w *Where_clause) Json_contains(field, path string, value any) *Where_clause {
+	w.clause("", Op_json, &json_condition{json_contains, field, path, value})
+	return w
+}
+
+//	Document at the path has any element in common with value (encoded as JSON): "JSON_OVERLAPS(JSON_EXTRACT(x, '$.a'), ?)"
+func (w *Where_clause) Json_overlaps(field, path string, value any) *Where_clause {
+	w.clause("", Op_json, &json_condition{json_overlaps, field, path, value})
+	return w
+}
+
+//	Set value at the path: "x=JSON_SET(x, '$.a', ?)"
+func (f *Fields_clause) Json_set(field, path string, value any) *Fields_clause {
+	f.json_edit(field, op_json_set, path, value)
+	return f
+}
+
+//	Remove value at the path: "x=JSON_REMOVE(x, '$.a')"
+func (f *Fields_clause) Json_remove(field, path string) *Fields_clause {
+	f.json_edit(field, op_json_remove, path, nil)
+	return f
+}
+
+//	Append value to the array at the path: "x=JSON_ARRAY_APPEND(x, '$.a', ?)"
+func (f *Fields_clause) Json_array_append(field, path string, value any) *Fields_clause {
+	f.json_edit(field, op_json_array_append, path, value)
+	return f
+}
+
+//	Edits of the same field are merged in one assignment
+func (f *Fields_clause) json_edit(field, function, path string, value any){
+	edit := json_edit{function, path, value}
+	for _, entry := range f.entries {
+		if entry.field == field {
+			if edits, ok := entry.value.(*json_edits); ok {
+				edits.list = append(edits.list, edit)
+				return
+			}
+		}
+	}
+	f.clause(field, op_update_json, &json_edits{[]json_edit{edit}})
+}
+
+func (q *Select_query) write_json_extract(ctx *compiler, e *json_extract) error {
+	if !valid_json_path(e.path, true) {
+		return fmt.Errorf("Invalid JSON path: %s", e.path)
+	}
+	if e.unquote {
+		ctx.sb.WriteString("JSON_UNQUOTE(")
+	}
+	ctx.sb.WriteString("JSON_EXTRACT(")
+	ctx.write_field(q.t, e.field)
+	ctx.sb.WriteString(", ")
+	write_json_path(ctx, e.path)
+	ctx.sb.WriteByte(')')
+	if e.unquote {
+		ctx.sb.WriteByte(')')
+	}
+	return nil
+}
+
+func (q *query_join) write_json_condition(ctx *compiler, c *json_condition) error {
+	//	Wildcards are only valid when extracting values
+	if !valid_json_path(c.path, c.function != json_contains) {
+		return fmt.Errorf("Invalid JSON path: %s", c.path)
+	}
+	switch c.function {
+	case json_eq:
+		if s, ok := c.value.(string); ok {
+			ctx.sb.WriteString("JSON_UNQUOTE(JSON_EXTRACT(")
+			ctx.write_field(q.t, c.field)
+			ctx.sb.WriteString(", ")
+			write_json_path(ctx, c.path)
+			ctx.sb.WriteString("))=?")
+			ctx.append_data(s)
+			break
+		}
+		ctx.sb.WriteString("JSON_EXTRACT(")
+		ctx.write_field(q.t, c.field)
+		ctx.sb.WriteString(", ")
+		write_json_path(ctx, c.path)
+		ctx.sb.WriteString(")=")
+		//	NULL never equals: Compare with JSON null
+		if c.value == nil {
+			ctx.sb.WriteString("JSON_EXTRACT('null', '$')")
+			break
+		}
+		if err := write_json_value(ctx, c.value); err != nil {
+			return fmt.Errorf("Invalid JSON value (%s): %w", c.field, err)
+		}
+		
+	case json_contains:
+		doc, err := json.Marshal(c.value)
+		if err != nil {
+			return fmt.Errorf("Invalid JSON value (%s): %w", c.field, err)
+		}
+		ctx.sb.WriteString("JSON_CONTAINS(")
+		ctx.write_field(q.t, c.field)
+		ctx.sb.WriteString(", ?, ")
+		write_json_path(ctx, c.path)
+		ctx.sb.WriteByte(')')
+		ctx.append_data(string(doc))
+		
+	case json_overlaps:
+		doc, err := json.Marshal(c.value)
+		if err != nil {
+			return fmt.Errorf("Invalid JSON value (%s): %w", c.field, err)
+		}
+		ctx.sb.WriteString("JSON_OVERLAPS(JSON_EXTRACT(")
+		ctx.write_field(q.t, c.field)
+		ctx.sb.WriteString(", ")
+		write_json_path(ctx, c.path)
+		ctx.sb.WriteString("), ?)")
+		ctx.append_data(string(doc))
+	}
+	return nil
+}
+
+//	Nested calls of the edits grouped by function: "x=JSON_REMOVE(JSON_SET(x, '$.a', ?, '$.b', ?), '$.c')"
+func (q *query_join) write_json_edits(ctx *compiler, field string, edits *json_edits) error {
+	groups := make([]int, 0, 2)	//	Start index of each group
+	for i, edit := range edits.list {
+		if !valid_json_path(edit.path, false) {
+			return fmt.Errorf("Invalid JSON path: %s", edit.path)
+		}
+		if i == 0 || edit.function != edits.list[i-1].function {
+			groups = append(groups, i)
+		}
+	}
+	
+	ctx.write_field(q.t, field)
+	ctx.sb.WriteByte('=')
+	for i := len(groups) - 1; i >= 0; i-- {
+		ctx.sb.WriteString(edits.list[groups[i]].function)
+		ctx.sb.WriteByte('(')
+	}
+	ctx.write_field(q.t, field)
+	for i, edit := range edits.list {
+		ctx.sb.WriteString(", ")
+		write_json_path(ctx, edit.path)
+		if edit.function != op_json_remove {
+			ctx.sb.WriteString(", ")
+			if err := write_json_value(ctx, edit.value); err != nil {
+				return fmt.Errorf("Invalid JSON value (%s): %w", field, err)
+			}
+		}
+		if i+1 == len(edits.list) || edit.function != edits.list[i+1].function {
+			ctx.sb.WriteByte(')')
+		}
+	}
+	return nil
+}
+
+//	Scalars are bound as is and other values are encoded as JSON documents
+func write_json_value(ctx *compiler, value any) error {
+	switch value.(type) {
+	case nil, string, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		ctx.sb.WriteByte('?')
+		ctx.append_data(value)
+		return nil
+	}
+	doc, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	ctx.sb.WriteString("JSON_EXTRACT(?, '$')")
+	ctx.append_data(string(doc))
+	return nil
+}
+
+//	Valid paths are written as literals (functional indexes on "x->>'$.a'" only match literal paths)
+func write_json_path(ctx *compiler, path string){
+	ctx.sb.WriteByte('\'')
+	ctx.sb.WriteString(path)
+	ctx.sb.WriteByte('\'')
+}
+
+//	Path syntax: "$", ".key", ".\"some key\"", "[0]" and wildcards ".*" and "[*]" (quoted keys can not contain quotes or backslashes)
+func valid_json_path(path string, wildcard bool) bool {
+	if path == "" || path[0] != '$' {
+		return false
+	}
+	for i := 1; i < len(path); {
+		switch path[i] {
+		case '.':
+			i++
+			switch {
+			case i == len(path):
+				return false
+			case path[i] == '*':
+				if !wildcard {
+					return false
+				}
+				i++
+			case path[i] == '"':
+				end := strings.IndexByte(path[i+1:], '"')
+				if end < 1 || strings.ContainsAny(path[i+1:i+1+end], `'\`) {
+					return false
+				}
+				i += end + 2
+			default:
+				start := i
+				for i < len(path) && json_path_key_char(path[i]) {
+					i++
+				}
+				if i == start {
+					return false
+				}
+			}
+		case '[':
+			end := strings.IndexByte(path[i:], ']')
+			if end < 2 {
+				return false
+			}
+			index := path[i+1 : i+end]
+			if index == "*" {
+				if !wildcard {
+					return false
+				}
+			} else {
+				for j := range len(index) {
+					if index[j] < '0' || index[j] > '9' {
+						return false
+					}
+				}
+			}
+			i += end + 1
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func json_path_key_char(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_' || c == '$'
+}
\ No newline at end of file
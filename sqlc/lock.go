@@ -0,0 +1,114 @@
+package sqlc
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	lock_for_update		= "FOR UPDATE"
+	lock_for_share		= "FOR SHARE"
+	lock_in_share_mode	= "LOCK IN SHARE MODE"
+	
+	lock_nowait			= "NOWAIT"
+	lock_skip_locked	= "SKIP LOCKED"
+)
+
+type select_lock struct {
+	mode			string
+	of				[]string	//	Lock only rows of these tables (base table name or join alias)
+	option			string		//	NOWAIT, SKIP LOCKED or WAIT n
+}
+
+func (q *Select_query) Lock_for_update() *Select_query {
+	q.lock.mode = lock_for_update
+	return q
+}
+
+//	Shared lock: Other transactions can read but not modify the rows
+func (q *Select_query) Lock_for_share() *Select_query {
+	q.lock.mode = lock_for_share
+	return q
+}
+
+//	Shared lock in MySQL 5.7 syntax (no lock options)
+func (q *Select_query) Lock_in_share_mode() *Select_query {
+	q.lock.mode = lock_in_share_mode
+	return q
+}
+
+//	Lock only rows of the tables: "FOR UPDATE OF u, c"
+func (q *Select_query) Lock_of(tables ...string) *Select_query {
+	q.lock.of = tables
+	return q
+}
+
+//	Fail immediately if a row is locked
+func (q *Select_query) Lock_nowait() *Select_query {
+	q.lock.option = lock_nowait
+	return q
+}
+
+//	Skip locked rows (i.e. worker queues)
+func (q *Select_query) Lock_skip_locked() *Select_query {
+	q.lock.option = lock_skip_locked
+	return q
+}
+
+//	Fail if a row is still locked after n seconds (MariaDB)
+func (q *Select_query) Lock_wait(seconds uint32) *Select_query {
+	q.lock.option = "WAIT "+strconv.FormatUint(uint64(seconds), 10)
+	return q
+}
+
+func (q *Select_query) compile_lock(ctx *compiler) error {
+	l := &q.lock
+	if l.mode == "" {
+		if l.option != "" || len(l.of) != 0 {
+			return fmt.Errorf("Lock options require FOR UPDATE or FOR SHARE")
+		}
+		return nil
+	}
+	if l.mode == lock_in_share_mode && (l.option != "" || len(l.of) != 0) {
+		return fmt.Errorf("Lock options are not supported with LOCK IN SHARE MODE")
+	}
+	
+	ctx.sb.WriteString(l.mode)
+	if len(l.of) != 0 {
+		ctx.sb.WriteString(" OF ")
+		for i, table := range l.of {
+			if i > 0 {
+				ctx.sb.WriteString(", ")
+			}
+			t, err := q.lock_table(ctx, table)
+			if err != nil {
+				return err
+			}
+			ctx.sb.WriteString(t)
+		}
+	}
+	if l.option != "" {
+		ctx.sb.WriteByte(' ')
+		ctx.sb.WriteString(l.option)
+	}
+	ctx.sb.WriteByte('\n')
+	return nil
+}
+
+//	Tables are referenced by alias if the query has table aliases
+func (q *Select_query) lock_table(ctx *compiler, table string) (string, error){
+	if table == q.table {
+		if ctx.use_alias {
+			return q.t, nil
+		}
+		return q.table, nil
+	}
+	if ctx.use_alias {
+		for _, j := range q.joins {
+			if j.t == table {
+				return j.t, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("Unknown lock table: %s", table)
+}
\ No newline at end of file
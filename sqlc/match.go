@@ -0,0 +1,128 @@
+package sqlc
+
+import "strings"
+
+const (
+	Mode_natural Match_mode = iota
+	Mode_boolean
+	Mode_query_expansion
+	
+	//	Operator chars in boolean mode
+	match_boolean_chars	= `+-<>()~*"@`
+)
+
+var sql_match_modes = [...]string{
+	Mode_natural:			" IN NATURAL LANGUAGE MODE",
+	Mode_boolean:			" IN BOOLEAN MODE",
+	Mode_query_expansion:	" WITH QUERY EXPANSION",
+}
+
+type (
+	Match_mode			uint8
+	
+	//	Boolean mode search query with escaped user input
+	Match_boolean_query struct {
+		terms			[]string
+	}
+	
+	match_against struct {
+		fields			[]string
+		query			string
+		mode			Match_mode
+	}
+)
+
+//	Full-text search: "MATCH(a, b) AGAINST (? IN BOOLEAN MODE)"
+func (w *Where_clause) Match(fields []string, query string, mode Match_mode) *Where_clause {
+	w.clause("", Op_match, &match_against{fields, query, mode})
+	return w
+}
+
+//	Full-text relevance as a select field (order by the alias)
+func (q *Select_query) Select_match(alias string, fields []string, query string, mode Match_mode) *Select_query {
+	q.select_fields = append(q.select_fields, select_field{
+		alias:	alias,
+		match:	&match_against{fields, query, mode},
+	})
+	return q
+}
+
+//	Order by the relevance alias of "Select_match" (highest first) before the fields of "Order" (call after "Order")
+func (q *Select_query) Order_relevance(alias string) *Select_query {
+	q.order			= append([]string{alias+" DESC"}, q.order...)
+	q.order_aliases	= append(q.order_aliases, alias)
+	return q
+}
+
+func Match_boolean() *Match_boolean_query {
+	return &Match_boolean_query{}
+}
+
+//	Words must be present: "+word"
+func (b *Match_boolean_query) Must(words string) *Match_boolean_query {
+	b.words("+", words, "")
+	return b
+}
+
+//	Words must not be present: "-word"
+func (b *Match_boolean_query) Must_not(words string) *Match_boolean_query {
+	b.words("-", words, "")
+	return b
+}
+
+//	Words are optional but increase relevance
+func (b *Match_boolean_query) Should(words string) *Match_boolean_query {
+	b.words("", words, "")
+	return b
+}
+
+//	Words starting with the prefix: "word*"
+func (b *Match_boolean_query) Prefix(words string) *Match_boolean_query {
+	b.words("", words, "*")
+	return b
+}
+
+//	Exact phrase must be present: "+\"some words\""
+func (b *Match_boolean_query) Phrase(phrase string) *Match_boolean_query {
+	if words := strings.Fields(escape_match(phrase)); len(words) != 0 {
+		b.terms = append(b.terms, `+"`+strings.Join(words, " ")+`"`)
+	}
+	return b
+}
+
+func (b *Match_boolean_query) String() string {
+	return strings.Join(b.terms, " ")
+}
+
+func (b *Match_boolean_query) words(prefix, words, suffix string){
+	for _, word := range strings.Fields(escape_match(words)) {
+		b.terms = append(b.terms, prefix+word+suffix)
+	}
+}
+
+//	Operator chars are replaced with spaces (boolean mode has no escape char)
+func escape_match(s string) string {
+	if !strings.ContainsAny(s, match_boolean_chars) {
+		return s
+	}
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(match_boolean_chars, r) {
+			return ' '
+		}
+		return r
+	}, s)
+}
+
+func (q *query_join) write_match(ctx *compiler, m *match_against){
+	ctx.sb.WriteString("MATCH(")
+	for i, f := range m.fields {
+		if i > 0 {
+			ctx.sb.WriteString(", ")
+		}
+		ctx.write_field(q.t, f)
+	}
+	ctx.sb.WriteString(") AGAINST (?")
+	ctx.sb.WriteString(sql_match_modes[m.mode])
+	ctx.sb.WriteByte(')')
+	ctx.append_data(m.query)
+}
\ No newline at end of file
@@ -35,6 +35,9 @@ type (
 		joined_t		bool		//	Joined on a non-base (pre-defined) table
 		joins 			[]join
 		optimize_joins	bool
+		ctes			[]*Cte
+		index_hints		[]index_hint
+		optimizer_hints	[]string
 	}
 	
 	join struct {
@@ -44,6 +47,7 @@ type (
 		join_t			[]string	//	Join on a non-base (pre-defined) table (table alias)
 		on				Join_conditions
 		depth			int
+		query			*Select_query	//	Join on a subquery (derived table)
 	}
 )
 
@@ -80,6 +84,11 @@ func (q *query_join) left_join_multi(table, t string, fields Join_conditions){
 	q.join_multi(join_left, table, t, fields)
 }
 
+func (q *query_join) join_subquery(mode string, query *Select_query, t string, fields Join_conditions){
+	q.join_multi(mode, "", t, fields)
+	q.joins[len(q.joins)-1].query = query
+}
+
 func (q *query_join) join(mode, table, t, field, field_foreign string){
 	fields := Join_conditions{{
 		Field:			field,
@@ -115,14 +124,20 @@ func (q *query_join) join_multi(mode, table, t string, fields Join_conditions){
 func (q *query_join) join_condition_foreign(fields Join_conditions) []string {
 	join_t := make([]string, 0, len(fields))
 	for _, f := range fields {
+		field_foreign := f.Field_foreign
 		if f.Fixed_value {
-			continue
+			//	Fixed value can be a field (Col)
+			col, ok := f.Field_value.(Col)
+			if !ok {
+				continue
+			}
+			field_foreign = string(col)
 		}
 		
 		// Join on a non-base (pre-defined) table
-		if i := strings.IndexByte(f.Field_foreign, '.'); i != -1 {
+		if i := strings.IndexByte(field_foreign, '.'); i != -1 {
 			q.joined_t	= true
-			join_t		= append(join_t, f.Field_foreign[:i])
+			join_t		= append(join_t, field_foreign[:i])
 		}
 	}
 	return join_t
@@ -180,6 +195,10 @@ func (q *query_join) resolve_alias_join_dependencies(list alias_collect) error {
 }
 
 func (q *query_join) compile_tables(ctx *compiler, t string) error {
+	if err := q.hint_error(); err != nil {
+		return err
+	}
+	
 	if ctx.use_alias {
 		//	Check for char collisions in joined tables
 		for i := range q.joins {
@@ -187,16 +206,19 @@ func (q *query_join) compile_tables(ctx *compiler, t string) error {
 			if _, ok := ctx.tables[alias]; ok {
 				return fmt.Errorf("Join table short already used: %s (%s)", alias, q.joins[i].table)
 			}
+			if _, ok := ctx.ctes[alias]; ok && alias != q.joins[i].table {
+				return fmt.Errorf("Join table short collides with CTE: %s (%s)", alias, q.joins[i].table)
+			}
 			ctx.tables[alias] = q.joins[i].table
 		}
 	}
 	
 	//	Get available char for base table (a-z)
-	if _, ok := ctx.tables[t]; ok {
+	if ctx.alias_used(t, q.table) {
 		var found bool
 		for i := range len(char_table) {
 			char := char_table[i : i+1]
-			if _, ok := ctx.tables[char]; !ok {
+			if !ctx.alias_used(char, q.table) {
 				t = char
 				found = true
 				break
@@ -213,12 +235,13 @@ func (q *query_join) compile_tables(ctx *compiler, t string) error {
 }
 
 func (q *query_join) compile_from(ctx *compiler){
-	ctx.sb.WriteString("FROM .")
-	ctx.sb.WriteString(q.table)
+	ctx.sb.WriteString("FROM ")
+	ctx.write_table(q.table)
 	if ctx.use_alias {
 		ctx.sb.WriteByte(' ')
 		ctx.sb.WriteString(q.t)
 	}
+	q.write_index_hints(ctx, "")
 	ctx.sb.WriteByte('\n')
 }
 
@@ -241,10 +264,17 @@ func (q *query_join) compile_joins(ctx *compiler, aliases alias_collect) error {
 		j := &joins_compile[i]	//	Avoid copying struct
 		
 		ctx.sb.WriteString(j.mode)
-		ctx.sb.WriteString(" .")
-		ctx.sb.WriteString(j.table)
+		ctx.sb.WriteByte(' ')
+		if j.query != nil {
+			if err := j.compile_subquery(ctx); err != nil {
+				return err
+			}
+		} else {
+			ctx.write_table(j.table)
+		}
 		ctx.sb.WriteByte(' ')
 		ctx.sb.WriteString(j.t)
+		q.write_index_hints(ctx, j.t)
 		
 		if j.mode == join_cross {
 			ctx.sb.WriteByte('\n')
@@ -262,20 +292,9 @@ func (q *query_join) compile_joins(ctx *compiler, aliases alias_collect) error {
 			ctx.sb.WriteString(jf.Field)
 			
 			if jf.Fixed_value {
-				sub_data, err := write_operator_condition(&ctx.sb, jf.Operator, jf.Field_value)
-				if err != nil {
+				if err := write_operator_condition(ctx, q.t, jf.Operator, jf.Field_value); err != nil {
 					return err
 				}
-				
-				if jf.Operator == Op_null || jf.Operator == Op_not_null {
-					continue
-				}
-				
-				if sub_data != nil {
-					ctx.append_data(sub_data)
-				} else {
-					ctx.append_data(jf.Field_value)
-				}
 			} else {
 				ctx.sb.WriteByte('=')
 				ctx.write_field(q.t, jf.Field_foreign)
@@ -287,6 +306,25 @@ func (q *query_join) compile_joins(ctx *compiler, aliases alias_collect) error {
 	return nil
 }
 
+func (j *join) compile_subquery(ctx *compiler) error {
+	ctx.sb.WriteString("(\n")
+	if err := compile_subquery(ctx, j.query); err != nil {
+		return err
+	}
+	ctx.sb.WriteByte(')')
+	
+	//	Table alias of the subquery must not be used inside the subquery
+	if j.query.joined && j.query.t == j.t {
+		return fmt.Errorf("Join subquery table short collides with subquery table: %s (%s)", j.t, j.query.table)
+	}
+	for _, sub := range j.query.joins {
+		if sub.t == j.t {
+			return fmt.Errorf("Join subquery table short collides with subquery table: %s (%s)", j.t, sub.table)
+		}
+	}
+	return nil
+}
+
 func (q *query_join) compile_optimize_joins(aliases alias_collect) []join {
 	joins_compile := aliases.filter(q.joins)
 	
@@ -314,17 +352,21 @@ func (q *query_join) compile_optimize_joins(aliases alias_collect) []join {
 	return joins_compile
 }
 
-func (q *query_join) write_update_field(ctx *compiler, field, operator string){
+func (q *query_join) write_update_field(ctx *compiler, field, operator string, value any) error {
 	switch operator {
 	case op_update_add:
 		ctx.write_field(q.t, field)
 		ctx.sb.WriteByte('=')
 		ctx.write_field(q.t, field)
 		ctx.sb.WriteString("+?")
+	case op_update_json:
+		return q.write_json_edits(ctx, field, value.(*json_edits))
 	default:
 		ctx.write_field(q.t, field)
 		ctx.sb.WriteString("=?")
 	}
+	ctx.append_data(value)
+	return nil
 }
 
 func (q *query_join) base_table_short() string {
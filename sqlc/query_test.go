@@ -9,6 +9,7 @@ package sqlc
 */
 
 import (
+	"time"
 	"slices"
 	"strings"
 	"reflect"
@@ -2100,4 +2101,3315 @@ WHERE name=test`
 	if got != want {
 		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
 	}
+}
+
+type test_schema map[string][]string
+
+func (s test_schema) Exists_table(table string) bool {
+	_, found := s[table]
+	return found
+}
+
+func (s test_schema) Exists_column(table, column string) bool {
+	return slices.Contains(s[table], column)
+}
+
+var validate_schema = test_schema{
+	"user":		{"id", "name", "email", "client_id", "time"},
+	"client":	{"id", "timeout", "active"},
+	"account":	{"id", "user_id", "key", "amount"},
+}
+
+func Benchmark_validate(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		run_validate_select(b)
+		run_validate_select_unknown(b)
+		run_validate_union(b)
+		run_validate_update(b)
+	}
+}
+
+func Test_validate(t *testing.T){
+	t.Run("validate select", func(t *testing.T){
+		run_validate_select(t)
+	})
+	
+	t.Run("validate select unknown", func(t *testing.T){
+		run_validate_select_unknown(t)
+	})
+	
+	t.Run("validate union", func(t *testing.T){
+		run_validate_union(t)
+	})
+	
+	t.Run("validate update", func(t *testing.T){
+		run_validate_update(t)
+	})
+}
+
+func run_validate_select(tb testing.TB){
+	subquery := Select("account").
+		Select([]string{
+			"key=key",
+			"sum|amount=amount",
+		})
+	
+	query := Select("user").
+		Select([]string{
+			"id",
+			"c.timeout=timeout",
+		}).
+		Select_json_condition("accounts", subquery, "user_id", "id").
+		Left_join("client", "c", "id", "client_id").
+		Where(Where().
+			Eq("email", "test").
+			In_subquery("id", Select("account").Select([]string{"user_id"})),
+		).
+		Order([]string{
+			"timeout DESC",
+			"<root>.name",
+		})
+	
+	if err := query.Validate(validate_schema); err != nil {
+		tb.Fatalf("Validate want: <nil>\nValidate got:\n%v", err)
+	}
+}
+
+func run_validate_select_unknown(tb testing.TB){
+	query := Select("user").
+		Select([]string{
+			"id",
+			"emial",
+			"x.timeout",
+		}).
+		Left_join("clients", "c", "id", "client_id").
+		Where(Where().
+			Eq("email", "test").
+			Eq("c.active", 1),
+		)
+	
+	want :=
+`Unknown table: clients
+Unknown column: user.emial
+Unknown table alias: x (x.timeout)`
+	err := query.Validate(validate_schema)
+	if err == nil || err.Error() != want {
+		tb.Fatalf("Validate want:\n%s\nValidate got:\n%v", want, err)
+	}
+}
+
+func run_validate_union(tb testing.TB){
+	query := Union_all().
+		Select([]string{
+			"col_id",
+			"col_mail",
+		}).
+		Union(Select("user").
+			Select([]string{
+				"id col_id",
+				"email col_email",
+			}),
+		).
+		Union(Select("client").
+			Select([]string{
+				"id col_id",
+				"timeout col_email",
+			}),
+		).
+		Order([]string{
+			"col_id DESC",
+		})
+	
+	want := "Unknown column: col_mail"
+	err := query.Validate(validate_schema)
+	if err == nil || err.Error() != want {
+		tb.Fatalf("Validate want:\n%s\nValidate got:\n%v", want, err)
+	}
+}
+
+func run_validate_update(tb testing.TB){
+	query := Update("user").
+		Fields(Map{
+			"name":		"test",
+			"time_login":	123,
+		}).
+		Where(Where().Eq("id", 1))
+	
+	want := "Unknown column: user.time_login"
+	err := query.Validate(validate_schema)
+	if err == nil || err.Error() != want {
+		tb.Fatalf("Validate want:\n%s\nValidate got:\n%v", want, err)
+	}
+	
+	query_insert := Insert("account").
+		Fields(Map{
+			"user_id":	1,
+			"amount":	10,
+		})
+	
+	if err := query_insert.Validate(validate_schema); err != nil {
+		tb.Fatalf("Validate want: <nil>\nValidate got:\n%v", err)
+	}
+}
+
+func Benchmark_seek(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		run_select_seek(b)
+		run_select_seek_mixed(b)
+		run_select_seek_before(b)
+	}
+}
+
+func Test_seek(t *testing.T){
+	t.Run("select seek", func(t *testing.T){
+		run_select_seek(t)
+	})
+	t.Run("select seek mixed directions", func(t *testing.T){
+		run_select_seek_mixed(t)
+	})
+	t.Run("select seek before", func(t *testing.T){
+		run_select_seek_before(t)
+	})
+	t.Run("select cursor", func(t *testing.T){
+		run_select_cursor(t)
+	})
+}
+
+func run_select_seek(tb testing.TB){
+	query := Select("user").
+		Select([]string{
+			"id",
+			"email",
+		}).
+		Where(Where().
+			Eq("status", 1),
+		).
+		Order([]string{
+			"time_created",
+			"id",
+		}).
+		Seek(1700000000, 123).
+		Limit(0, 1000)
+	
+	sql, _, _ := query.Compile()
+	
+	want :=
+`SELECT id, email
+FROM .user
+WHERE (time_created, id)>(?,?) AND status=?
+ORDER BY time_created, id
+LIMIT 0,1000`
+	got := strings.TrimSpace(sql)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+	
+	want =
+`SELECT id, email
+FROM .user
+WHERE (time_created, id)>(1700000000,123) AND status=1
+ORDER BY time_created, id
+LIMIT 0,1000`
+	got = SQL_debug(query)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+}
+
+func run_select_seek_mixed(tb testing.TB){
+	query := Select("user").
+		Select([]string{
+			"id",
+			"c.timeout",
+		}).
+		Left_join("client", "c", "id", "client_id").
+		Order([]string{
+			"c.timeout DESC",
+			"id",
+		}).
+		Seek(30, 123).
+		Limit(0, 10)
+	
+	sql, _, _ := query.Compile()
+	
+	want :=
+`SELECT u.id, c.timeout
+FROM .user u
+LEFT JOIN .client c ON c.id=u.client_id
+WHERE (c.timeout<? OR c.timeout=? AND u.id>?)
+ORDER BY c.timeout DESC, u.id
+LIMIT 0,10`
+	got := strings.TrimSpace(sql)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+	
+	want =
+`SELECT u.id, c.timeout
+FROM .user u
+LEFT JOIN .client c ON c.id=u.client_id
+WHERE (c.timeout<30 OR c.timeout=30 AND u.id>123)
+ORDER BY c.timeout DESC, u.id
+LIMIT 0,10`
+	got = SQL_debug(query)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+}
+
+func run_select_seek_before(tb testing.TB){
+	query := Select("user").
+		Select([]string{
+			"id",
+			"email",
+		}).
+		Order([]string{
+			"email DESC",
+		}).
+		Seek_before("test").
+		Limit(0, 10)
+	
+	if !query.Reversed() {
+		tb.Fatalf("Seek before must be reversed")
+	}
+	
+	sql, _, _ := query.Compile()
+	
+	want :=
+`SELECT id, email
+FROM .user
+WHERE email>?
+ORDER BY email
+LIMIT 0,10`
+	got := strings.TrimSpace(sql)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+	
+	want =
+`SELECT id, email
+FROM .user
+WHERE email>test
+ORDER BY email
+LIMIT 0,10`
+	got = SQL_debug(query)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+}
+
+func run_select_cursor(tb testing.TB){
+	Cursor_key([]byte("secret"))
+	
+	query := func() *Select_query {
+		return Select("user").
+			Select([]string{
+				"id",
+				"email",
+			}).
+			Order([]string{
+				"time_created DESC",
+				"id DESC",
+			}).
+			Limit(0, 10)
+	}
+	
+	created := time.Date(2024, 1, 2, 3, 4, 5, 6000, time.UTC)
+	next, err := query().Next_cursor(created, uint64(123))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	prev, err := query().Prev_cursor(created, uint64(124))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	
+	q := query().Cursor(next)
+	sql, data, err := q.Compile()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	want :=
+`SELECT id, email
+FROM .user
+WHERE (time_created, id)<(?,?)
+ORDER BY time_created DESC, id DESC
+LIMIT 0,10`
+	if got := strings.TrimSpace(sql); got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+	if want_data := []any{created, uint64(123)}; !reflect.DeepEqual(want_data, data) {
+		tb.Fatalf("Data want:\n%v\nData got:\n%v", want_data, data)
+	}
+	
+	q = query().Cursor(prev)
+	sql, _, err = q.Compile()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	want =
+`SELECT id, email
+FROM .user
+WHERE (time_created, id)>(?,?)
+ORDER BY time_created, id
+LIMIT 0,10`
+	if got := strings.TrimSpace(sql); got != want || !q.Reversed() {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+	
+	//	Tampered token
+	payload, sig, _ := strings.Cut(next, ".")
+	tampered := payload[:len(payload)-2]+"x"+payload[len(payload)-1:]+"."+sig
+	if _, _, err := query().Cursor(tampered).Compile(); err == nil {
+		tb.Fatalf("Tampered cursor must fail")
+	}
+	
+	//	Token from another query
+	q = Select("user").
+		Select([]string{
+			"id",
+		}).
+		Order([]string{
+			"id",
+		}).
+		Cursor(next)
+	if _, _, err := q.Compile(); err == nil || err.Error() != "Cursor does not match query" {
+		tb.Fatalf("Cursor from other query must fail: %v", err)
+	}
+	
+	if _, err := query().Next_cursor(123); err == nil {
+		tb.Fatalf("Cursor with missing values must fail")
+	}
+}
+
+func Benchmark_count(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		run_select_count(b)
+		run_select_count_distinct(b)
+		run_select_count_group(b)
+	}
+}
+
+func Test_count(t *testing.T){
+	t.Run("select count", func(t *testing.T){
+		run_select_count(t)
+	})
+	t.Run("select count distinct", func(t *testing.T){
+		run_select_count_distinct(t)
+	})
+	t.Run("select count group", func(t *testing.T){
+		run_select_count_group(t)
+	})
+	t.Run("select count inner join", func(t *testing.T){
+		run_select_count_inner_join(t)
+	})
+	t.Run("select count distinct group", func(t *testing.T){
+		run_select_count_distinct_group(t)
+	})
+	t.Run("union count", func(t *testing.T){
+		run_union_count(t)
+	})
+}
+
+func run_select_count(tb testing.TB){
+	query := Select("user").
+		Select([]string{
+			"id",
+			"c.timeout",
+			"g.name",
+		}).
+		Select_json("logins", Select("login").
+			Select([]string{
+				"id",
+				"time",
+			}),
+		).
+		Left_join("client", "c", "id", "client_id").
+		Left_join("group", "g", "id", "group_id").
+		Where(Where().
+			Eq("c.timeout", 30),
+		).
+		Order([]string{
+			"g.name",
+		}).
+		Limit(0, 10).
+		Optimize_joins()
+	
+	sql, _, _ := query.Count_query().Compile()
+	
+	want :=
+`SELECT COUNT(*)
+FROM .user u
+LEFT JOIN .client c ON c.id=u.client_id
+LEFT JOIN .group g ON g.id=u.group_id
+WHERE c.timeout=?`
+	got := strings.TrimSpace(sql)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+	
+	want =
+`SELECT COUNT(*)
+FROM .user u
+LEFT JOIN .client c ON c.id=u.client_id
+LEFT JOIN .group g ON g.id=u.group_id
+WHERE c.timeout=30`
+	got = SQL_debug(query.Count_query())
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+	
+	//	Original query is unchanged
+	sql, _, _ = query.Compile()
+	if !strings.Contains(sql, "LEFT JOIN .group g") || !strings.Contains(sql, "LIMIT 0,10") {
+		tb.Fatalf("Count query must not change the original query:\n%s", sql)
+	}
+}
+
+func run_select_count_distinct(tb testing.TB){
+	query := Select("user").
+		Select_distinct([]string{
+			"email",
+		}).
+		Where(Where().
+			Eq("status", 1),
+		).
+		Order([]string{
+			"email",
+		}).
+		Limit(0, 10)
+	
+	sql, _, _ := query.Count_query().Compile()
+	
+	want :=
+`SELECT COUNT(*)
+FROM (
+SELECT DISTINCT email
+FROM .user
+WHERE status=?
+) t`
+	got := strings.TrimSpace(sql)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+}
+
+func run_select_count_group(tb testing.TB){
+	query := Select("user").
+		Select([]string{
+			"email",
+			"count|id",
+		}).
+		Where(Where().
+			Eq("status", 1),
+		).
+		Group([]string{
+			"email",
+		}).
+		Limit(0, 10)
+	
+	sql, data, _ := query.Count_query().Compile()
+	
+	want :=
+`SELECT COUNT(*)
+FROM (
+SELECT 1
+FROM .user
+WHERE status=?
+GROUP BY email
+) t`
+	got := strings.TrimSpace(sql)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+	if want_data := []any{1}; !reflect.DeepEqual(want_data, data) {
+		tb.Fatalf("Data want:\n%v\nData got:\n%v", want_data, data)
+	}
+}
+
+func run_select_count_inner_join(tb testing.TB){
+	query := Select("user").
+		Select([]string{
+			"id",
+			"c.timeout",
+			"g.name",
+		}).
+		Inner_join("client", "c", "id", "client_id").
+		Left_join("group", "g", "id", "c.group_id").
+		Where(Where().
+			Eq("name", "test"),
+		).
+		Limit(0, 10).
+		Optimize_joins()
+	
+	joins := slices.Clone(query.joins)
+	
+	want :=
+`SELECT COUNT(*)
+FROM .user u
+JOIN .client c ON c.id=u.client_id
+LEFT JOIN .group g ON g.id=c.group_id
+WHERE u.name=test`
+	got := SQL_debug(query.Count_query())
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+	
+	//	Joins of the original query are unchanged
+	if !reflect.DeepEqual(joins, query.joins) {
+		tb.Fatalf("Count query must not change the joins of the original query")
+	}
+}
+
+func run_select_count_distinct_group(tb testing.TB){
+	query := Select("user").
+		Select_distinct([]string{
+			"client_id",
+			"count|id=n",
+		}).
+		Group([]string{
+			"client_id",
+		}).
+		Having(Where().
+			Gt("n", 1),
+		).
+		Limit(0, 10)
+	
+	want :=
+`SELECT COUNT(*)
+FROM (
+SELECT DISTINCT client_id, COUNT(id) n
+FROM .user
+GROUP BY client_id
+HAVING n>1
+) t`
+	got := SQL_debug(query.Count_query())
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+}
+
+func run_union_count(tb testing.TB){
+	query := Union_all().
+		Select([]string{
+			"id",
+		}).
+		Union(Select("user").
+			Select([]string{
+				"id",
+			}),
+		).
+		Union(Select("group").
+			Select([]string{
+				"id",
+			}).
+			Where(Where().
+				Eq("status", 1),
+			),
+		).
+		Order([]string{
+			"id",
+		}).
+		Limit(0, 10)
+	
+	sql, _, _ := query.Count_query().Compile()
+	
+	want :=
+`SELECT COUNT(*)
+FROM (
+SELECT id
+FROM (
+SELECT id
+FROM .user
+UNION ALL
+SELECT id
+FROM .group
+WHERE status=?
+) t
+
+) t`
+	got := strings.TrimSpace(sql)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+}
+
+func Benchmark_having(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		run_select_having(b)
+		run_select_having_optimize(b)
+	}
+}
+
+func Test_having(t *testing.T){
+	t.Run("select having", func(t *testing.T){
+		run_select_having(t)
+	})
+	t.Run("select having optimize joins", func(t *testing.T){
+		run_select_having_optimize(t)
+	})
+	t.Run("union having", func(t *testing.T){
+		run_union_having(t)
+	})
+	t.Run("validate having", func(t *testing.T){
+		run_validate_having(t)
+	})
+}
+
+func run_select_having(tb testing.TB){
+	query := Select("account").
+		Select([]string{
+			"user_id",
+			"sum|amount=total",
+			"count|id=num",
+		}).
+		Where(Where().
+			Gt("amount", 10),
+		).
+		Group([]string{
+			"user_id",
+		}).
+		Having(Where().
+			Gt("total", 0).
+			Lt("num", 100).
+			Gt_eq("max|amount", 50),
+		).
+		Order([]string{
+			"total DESC",
+		}).
+		Limit(0, 10)
+	
+	sql, data, _ := query.Compile()
+	
+	want :=
+`SELECT user_id, SUM(amount) total, COUNT(id) num
+FROM .account
+WHERE amount>?
+GROUP BY user_id
+HAVING total>? AND num<? AND MAX(amount)>=?
+ORDER BY total DESC
+LIMIT 0,10`
+	got := strings.TrimSpace(sql)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+	if want_data := []any{10, 0, 100, 50}; !reflect.DeepEqual(want_data, data) {
+		tb.Fatalf("Data want:\n%v\nData got:\n%v", want_data, data)
+	}
+}
+
+func run_select_having_optimize(tb testing.TB){
+	query := Select("account").
+		Select([]string{
+			"user_id",
+		}).
+		Left_join("user", "u", "id", "user_id").
+		Left_join("client", "c", "id", "u.client_id").
+		Left_join("client", "x", "id", "user_id").
+		Where(Where().
+			Eq("u.name", "test"),
+		).
+		Group([]string{
+			"user_id",
+		}).
+		Having(Where().
+			Gt("sum|c.timeout", 10),
+		).
+		Optimize_joins()
+	
+	sql, _, _ := query.Compile()
+	
+	want :=
+`SELECT a.user_id
+FROM .account a
+LEFT JOIN .user u ON u.id=a.user_id
+LEFT JOIN .client c ON c.id=u.client_id
+WHERE u.name=?
+GROUP BY a.user_id
+HAVING SUM(c.timeout)>?`
+	got := strings.TrimSpace(sql)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+	
+	want =
+`SELECT a.user_id
+FROM .account a
+LEFT JOIN .user u ON u.id=a.user_id
+LEFT JOIN .client c ON c.id=u.client_id
+WHERE u.name=test
+GROUP BY a.user_id
+HAVING SUM(c.timeout)>10`
+	got = SQL_debug(query)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+}
+
+func run_union_having(tb testing.TB){
+	query := Union_all().
+		Select([]string{
+			"user_id",
+			"sum|amount=total",
+		}).
+		Union(Select("account").
+			Select([]string{
+				"user_id",
+				"amount",
+			}).
+			Where(Where().
+				Eq("key", "a"),
+			),
+		).
+		Union(Select("account").
+			Select([]string{
+				"user_id",
+				"amount",
+			}).
+			Where(Where().
+				Eq("key", "b"),
+			),
+		).
+		Group([]string{
+			"user_id",
+		}).
+		Having(Where().
+			Gt("total", 0),
+		)
+	
+	want :=
+`SELECT user_id, SUM(amount) total
+FROM (
+SELECT user_id, amount
+FROM .account
+WHERE key=a
+UNION ALL
+SELECT user_id, amount
+FROM .account
+WHERE key=b
+) t
+GROUP BY user_id
+HAVING total>0`
+	got := SQL_debug(query)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+}
+
+func run_validate_having(tb testing.TB){
+	query := Select("account").
+		Select([]string{
+			"user_id",
+			"sum|amount=total",
+		}).
+		Group([]string{
+			"user_id",
+		}).
+		Having(Where().
+			Gt("total", 0).
+			Gt("sum|amont", 0),
+		)
+	
+	want := "Unknown column: account.amont"
+	err := query.Validate(validate_schema)
+	if err == nil || err.Error() != want {
+		tb.Fatalf("Validate want:\n%s\nValidate got:\n%v", want, err)
+	}
+}
+
+func Benchmark_window(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		run_select_window(b)
+		run_select_window_named(b)
+	}
+}
+
+func Test_window(t *testing.T){
+	t.Run("select window", func(t *testing.T){
+		run_select_window(t)
+	})
+	t.Run("select window named", func(t *testing.T){
+		run_select_window_named(t)
+	})
+	t.Run("select window optimize joins", func(t *testing.T){
+		run_select_window_optimize(t)
+	})
+	t.Run("select window errors", func(t *testing.T){
+		run_select_window_errors(t)
+	})
+	t.Run("select window fields", func(t *testing.T){
+		run_select_window_fields(t)
+	})
+	t.Run("validate window", func(t *testing.T){
+		run_validate_window(t)
+	})
+}
+
+func run_select_window(tb testing.TB){
+	query := Select("account").
+		Select([]string{
+			"id",
+			"user_id",
+			"amount",
+		}).
+		Select_window("num", Row_number().
+			Over(Window().
+				Partition("user_id").
+				Order("amount DESC"),
+			),
+		).
+		Select_window("prev", Lag("amount", 1).
+			Default(0).
+			Over(Window().
+				Order("id"),
+			),
+		).
+		Select_window("running", Sum("amount").
+			Over(Window().
+				Partition("user_id").
+				Order("id").
+				Rows(Unbounded_preceding, Current_row),
+			),
+		).
+		Select_window("moving", Avg("amount").
+			Over(Window().
+				Order("id").
+				Rows(Preceding(2), Following(1)),
+			),
+		).
+		Select_window("pos", Dense_rank().
+			Over(nil),
+		).
+		Where(Where().
+			Gt("amount", 10),
+		)
+	
+	sql, data, _ := query.Compile()
+	
+	want :=
+`SELECT id, user_id, amount, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY amount DESC) num, LAG(amount, 1, ?) OVER (ORDER BY id) prev, SUM(amount) OVER (PARTITION BY user_id ORDER BY id ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW) running, AVG(amount) OVER (ORDER BY id ROWS BETWEEN 2 PRECEDING AND 1 FOLLOWING) moving, DENSE_RANK() OVER () pos
+FROM .account
+WHERE amount>?`
+	got := strings.TrimSpace(sql)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+	if want_data := []any{0, 10}; !reflect.DeepEqual(want_data, data) {
+		tb.Fatalf("Data want:\n%v\nData got:\n%v", want_data, data)
+	}
+}
+
+func run_select_window_named(tb testing.TB){
+	query := Select("account").
+		Select([]string{
+			"id",
+		}).
+		Left_join("user", "u", "id", "user_id").
+		Select_window("num", Rank().
+			Over_window("w"),
+		).
+		Select_window("next", Lead("u.name", 1).
+			Over_window("w"),
+		).
+		Window("w", Window().
+			Partition("u.client_id").
+			Order("amount").
+			Range(Unbounded_preceding, Unbounded_following),
+		).
+		Order([]string{
+			"id",
+		})
+	
+	want :=
+`SELECT a.id, RANK() OVER w num, LEAD(u.name, 1) OVER w next
+FROM .account a
+LEFT JOIN .user u ON u.id=a.user_id
+WINDOW w AS (PARTITION BY u.client_id ORDER BY a.amount RANGE BETWEEN UNBOUNDED PRECEDING AND UNBOUNDED FOLLOWING)
+ORDER BY a.id`
+	got := SQL_debug(query)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+}
+
+func run_select_window_optimize(tb testing.TB){
+	query := Select("account").
+		Select([]string{
+			"id",
+		}).
+		Left_join("user", "u", "id", "user_id").
+		Left_join("client", "c", "id", "u.client_id").
+		Left_join("client", "x", "id", "user_id").
+		Select_window("num", Row_number().
+			Over(Window().
+				Partition("c.id"),
+			),
+		).
+		Optimize_joins()
+	
+	want :=
+`SELECT a.id, ROW_NUMBER() OVER (PARTITION BY c.id) num
+FROM .account a
+LEFT JOIN .user u ON u.id=a.user_id
+LEFT JOIN .client c ON c.id=u.client_id`
+	got := SQL_debug(query)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+}
+
+func run_select_window_errors(tb testing.TB){
+	_, _, err := Select("account").
+		Select_window("num", Rank().
+			Over_window("w"),
+		).
+		Compile()
+	if want := "Unknown window: w"; err == nil || err.Error() != want {
+		tb.Fatalf("Error want:\n%s\nError got:\n%v", want, err)
+	}
+	
+	_, _, err = Select("account").
+		Select_window("", Rank().
+			Over(nil),
+		).
+		Compile()
+	if want := "Window function RANK must have an alias"; err == nil || err.Error() != want {
+		tb.Fatalf("Error want:\n%s\nError got:\n%v", want, err)
+	}
+	
+	_, _, err = Select("account").
+		Select_window("total", Sum("amount").
+			Over(Window().
+				Rows("1) FROM user; --", Current_row),
+			),
+		).
+		Compile()
+	if want := "Invalid window frame bound: 1) FROM user; --"; err == nil || err.Error() != want {
+		tb.Fatalf("Error want:\n%s\nError got:\n%v", want, err)
+	}
+	
+	_, _, err = Select("account").
+		Window("w", Window().
+			Range(Preceding(1), "-1 FOLLOWING"),
+		).
+		Compile()
+	if want := "Invalid window frame bound: -1 FOLLOWING"; err == nil || err.Error() != want {
+		tb.Fatalf("Error want:\n%s\nError got:\n%v", want, err)
+	}
+}
+
+func run_select_window_fields(tb testing.TB){
+	//	Select replaces the fields of earlier calls but keeps window functions
+	query := Select("account").
+		Select([]string{
+			"user_id",
+		}).
+		Select_window("num", Row_number().
+			Over(Window().
+				Order("amount DESC"),
+			),
+		).
+		Select([]string{
+			"id",
+			"amount",
+		})
+	
+	want :=
+`SELECT id, amount, ROW_NUMBER() OVER (ORDER BY amount DESC) num
+FROM .account`
+	got := SQL_debug(query)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+}
+
+func run_validate_window(tb testing.TB){
+	query := Select("account").
+		Select([]string{
+			"id",
+		}).
+		Select_window("running", Sum("amont").
+			Over(Window().
+				Partition("user_id").
+				Order("tim DESC"),
+			),
+		)
+	
+	want := "Unknown column: account.amont\nUnknown column: account.tim"
+	err := query.Validate(validate_schema)
+	if err == nil || err.Error() != want {
+		tb.Fatalf("Validate want:\n%s\nValidate got:\n%v", want, err)
+	}
+}
+
+func Benchmark_with(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		run_select_with(b)
+		run_select_with_recursive(b)
+	}
+}
+
+func Test_with(t *testing.T){
+	t.Run("select with", func(t *testing.T){
+		run_select_with(t)
+	})
+	t.Run("select with recursive", func(t *testing.T){
+		run_select_with_recursive(t)
+	})
+	t.Run("select with alias collision", func(t *testing.T){
+		run_select_with_alias(t)
+	})
+	t.Run("update with", func(t *testing.T){
+		run_update_with(t)
+	})
+	t.Run("delete with", func(t *testing.T){
+		run_delete_with(t)
+	})
+	t.Run("validate with", func(t *testing.T){
+		run_validate_with(t)
+	})
+}
+
+func run_select_with(tb testing.TB){
+	query := Select("totals").
+		With(With("totals", Select("account").
+			Select([]string{
+				"user_id",
+				"sum|amount=total",
+			}).
+			Where(Where().
+				Gt("amount", 10),
+			).
+			Group([]string{
+				"user_id",
+			}),
+		)).
+		Select([]string{
+			"user_id",
+			"total",
+			"u.name",
+		}).
+		Left_join("user", "u", "id", "user_id").
+		Where(Where().
+			Eq("u.name", "test"),
+		)
+	
+	sql, data, _ := query.Compile()
+	
+	want :=
+`WITH totals AS (
+SELECT user_id, SUM(amount) total
+FROM .account
+WHERE amount>?
+GROUP BY user_id
+)
+SELECT t.user_id, t.total, u.name
+FROM totals t
+LEFT JOIN .user u ON u.id=t.user_id
+WHERE u.name=?`
+	got := strings.TrimSpace(sql)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+	if want_data := []any{10, "test"}; !reflect.DeepEqual(want_data, data) {
+		tb.Fatalf("Data want:\n%v\nData got:\n%v", want_data, data)
+	}
+}
+
+func run_select_with_recursive(tb testing.TB){
+	query := Select("tree").
+		With(With_recursive("tree",
+			Select("category").
+				Select([]string{
+					"id",
+					"parent_id",
+					"name",
+				}).
+				Where(Where().
+					Eq("id", 1),
+				),
+			Select("category").
+				Select([]string{
+					"id",
+					"parent_id",
+					"name",
+				}).
+				Inner_join("tree", "t", "id", "parent_id"),
+		)).
+		Select([]string{
+			"id",
+			"name",
+		}).
+		Where(Where().
+			In_subquery("id", Select("tree").
+				Select([]string{
+					"id",
+				}).
+				Where(Where().
+					Not_null("parent_id"),
+				),
+			),
+		)
+	
+	want :=
+`WITH RECURSIVE tree AS (
+SELECT id, parent_id, name
+FROM .category
+WHERE id=1
+UNION ALL
+SELECT c.id, c.parent_id, c.name
+FROM .category c
+JOIN tree t ON t.id=c.parent_id
+)
+SELECT id, name
+FROM tree
+WHERE id IN (
+SELECT id
+FROM tree
+WHERE parent_id IS NOT NULL
+)`
+	got := SQL_debug(query)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+}
+
+func run_select_with_alias(tb testing.TB){
+	query := Select("account").
+		With(With("a", Select("user").
+			Select([]string{
+				"id",
+			}),
+		)).
+		Select([]string{
+			"id",
+		}).
+		Inner_join("a", "x", "id", "user_id")
+	
+	want :=
+`WITH a AS (
+SELECT id
+FROM .user
+)
+SELECT b.id
+FROM .account b
+JOIN a x ON x.id=b.user_id`
+	got := SQL_debug(query)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+	
+	_, _, err := Select("account").
+		With(With("u", Select("user"))).
+		Left_join("user", "u", "id", "user_id").
+		Compile()
+	if want := "Join table short collides with CTE: u (user)"; err == nil || err.Error() != want {
+		tb.Fatalf("Error want:\n%s\nError got:\n%v", want, err)
+	}
+}
+
+func run_update_with(tb testing.TB){
+	query := Update("account").
+		With(With("blocked", Select("user").
+			Select([]string{
+				"id",
+			}).
+			Where(Where().
+				Eq("name", "test"),
+			),
+		)).
+		Fields(map[string]any{
+			"amount": 0,
+		}).
+		Where(Where().
+			In_subquery("user_id", Select("blocked").
+				Select([]string{
+					"id",
+				}),
+			),
+		)
+	
+	sql, data, _ := query.Compile()
+	
+	want :=
+`WITH blocked AS (
+SELECT id
+FROM .user
+WHERE name=?
+)
+UPDATE .account
+SET amount=?
+WHERE user_id IN (
+SELECT id
+FROM blocked
+)`
+	got := strings.TrimSpace(sql)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+	if want_data := []any{"test", 0}; !reflect.DeepEqual(want_data, data) {
+		tb.Fatalf("Data want:\n%v\nData got:\n%v", want_data, data)
+	}
+}
+
+func run_delete_with(tb testing.TB){
+	query := Delete("account").
+		With(With("blocked", Select("user").
+			Select([]string{
+				"id",
+			}).
+			Where(Where().
+				Eq("name", "test"),
+			),
+		)).
+		Left_join("blocked", "b", "id", "user_id").
+		Where(Where().
+			Not_null("b.id"),
+		)
+	
+	want :=
+`WITH blocked AS (
+SELECT id
+FROM .user
+WHERE name=test
+)
+DELETE a FROM .account a
+LEFT JOIN blocked b ON b.id=a.user_id
+WHERE b.id IS NOT NULL`
+	got := SQL_debug(query)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+}
+
+func run_validate_with(tb testing.TB){
+	query := Select("totals").
+		With(With("totals", Select("account").
+			Select([]string{
+				"user_id",
+				"sum|amount=total",
+			}).
+			Group([]string{
+				"user_id",
+			}),
+		)).
+		Select([]string{
+			"user_id",
+			"total",
+			"u.name",
+		}).
+		Left_join("user", "u", "id", "user_id")
+	
+	if err := query.Validate(validate_schema); err != nil {
+		tb.Fatalf("Validate want: nil\nValidate got:\n%v", err)
+	}
+	
+	query.Select([]string{
+		"totl",
+	})
+	want := "Unknown column: totals.totl"
+	err := query.Validate(validate_schema)
+	if err == nil || err.Error() != want {
+		tb.Fatalf("Validate want:\n%s\nValidate got:\n%v", want, err)
+	}
+}
+
+func Benchmark_join_subquery(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		run_select_join_subquery(b)
+		run_select_join_subquery_optimize(b)
+	}
+}
+
+func Test_join_subquery(t *testing.T){
+	t.Run("select join subquery", func(t *testing.T){
+		run_select_join_subquery(t)
+	})
+	t.Run("select join subquery optimize joins", func(t *testing.T){
+		run_select_join_subquery_optimize(t)
+	})
+	t.Run("select join subquery alias collision", func(t *testing.T){
+		run_select_join_subquery_alias(t)
+	})
+	t.Run("validate join subquery", func(t *testing.T){
+		run_validate_join_subquery(t)
+	})
+}
+
+func run_select_join_subquery(tb testing.TB){
+	latest := Select("account").
+		Select([]string{
+			"user_id",
+			"max|id=last_id",
+		}).
+		Where(Where().
+			Gt("amount", 0),
+		).
+		Group([]string{
+			"user_id",
+		})
+	
+	query := Select("user").
+		Select([]string{
+			"id",
+			"name",
+			"l.last_id",
+		}).
+		Left_join_subquery(latest, "l", Join_conditions{{
+			Field:			"user_id",
+			Field_foreign:	"id",
+		}}).
+		Where(Where().
+			Eq("name", "test"),
+		)
+	
+	sql, data, _ := query.Compile()
+	
+	want :=
+`SELECT u.id, u.name, l.last_id
+FROM .user u
+LEFT JOIN (
+SELECT user_id, MAX(id) last_id
+FROM .account
+WHERE amount>?
+GROUP BY user_id
+) l ON l.user_id=u.id
+WHERE u.name=?`
+	got := strings.TrimSpace(sql)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+	if want_data := []any{0, "test"}; !reflect.DeepEqual(want_data, data) {
+		tb.Fatalf("Data want:\n%v\nData got:\n%v", want_data, data)
+	}
+}
+
+func run_select_join_subquery_optimize(tb testing.TB){
+	totals := Select("account").
+		Select([]string{
+			"user_id",
+			"sum|amount=total",
+		}).
+		Where(Where().
+			Gt("amount", 5),
+		).
+		Group([]string{
+			"user_id",
+		})
+	
+	query := Select("user").
+		Select([]string{
+			"id",
+			"s.total",
+		}).
+		Inner_join_subquery(totals, "s", Join_conditions{{
+			Field:			"user_id",
+			Field_foreign:	"c.id",
+		}}).
+		Left_join("client", "c", "id", "client_id").
+		Left_join_subquery(Select("account"), "x", Join_conditions{{
+			Field:			"user_id",
+			Field_foreign:	"id",
+		}}).
+		Where(Where().
+			Eq("name", "test"),
+		).
+		Optimize_joins()
+	
+	sql, data, _ := query.Compile()
+	
+	want :=
+`SELECT u.id, s.total
+FROM .user u
+LEFT JOIN .client c ON c.id=u.client_id
+JOIN (
+SELECT user_id, SUM(amount) total
+FROM .account
+WHERE amount>?
+GROUP BY user_id
+) s ON s.user_id=c.id
+WHERE u.name=?`
+	got := strings.TrimSpace(sql)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+	if want_data := []any{5, "test"}; !reflect.DeepEqual(want_data, data) {
+		tb.Fatalf("Data want:\n%v\nData got:\n%v", want_data, data)
+	}
+}
+
+func run_select_join_subquery_alias(tb testing.TB){
+	_, _, err := Select("user").
+		Inner_join_subquery(Select("account").
+			Left_join("client", "c", "id", "user_id"),
+			"c", Join_conditions{{
+				Field:			"user_id",
+				Field_foreign:	"id",
+			}},
+		).
+		Compile()
+	if want := "Join subquery table short collides with subquery table: c (client)"; err == nil || err.Error() != want {
+		tb.Fatalf("Error want:\n%s\nError got:\n%v", want, err)
+	}
+	
+	_, _, err = Select("user").
+		Inner_join_subquery(Select("account").
+			Left_join("client", "c", "id", "user_id"),
+			"a", Join_conditions{{
+				Field:			"user_id",
+				Field_foreign:	"id",
+			}},
+		).
+		Compile()
+	if want := "Join subquery table short collides with subquery table: a (account)"; err == nil || err.Error() != want {
+		tb.Fatalf("Error want:\n%s\nError got:\n%v", want, err)
+	}
+}
+
+func run_validate_join_subquery(tb testing.TB){
+	latest := Select("account").
+		Select([]string{
+			"user_id",
+			"max|id=last_id",
+		}).
+		Group([]string{
+			"user_id",
+		})
+	
+	query := Select("user").
+		Select([]string{
+			"id",
+			"l.last_id",
+		}).
+		Left_join_subquery(latest, "l", Join_conditions{{
+			Field:			"user_id",
+			Field_foreign:	"id",
+		}})
+	
+	if err := query.Validate(validate_schema); err != nil {
+		tb.Fatalf("Validate want: nil\nValidate got:\n%v", err)
+	}
+	
+	query.Select([]string{
+		"l.lst",
+	})
+	want := "Unknown column: l.lst"
+	err := query.Validate(validate_schema)
+	if err == nil || err.Error() != want {
+		tb.Fatalf("Validate want:\n%s\nValidate got:\n%v", want, err)
+	}
+}
+
+func Benchmark_exists(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		run_select_exists(b)
+		run_select_not_exists(b)
+	}
+}
+
+func Test_exists(t *testing.T){
+	t.Run("select exists", func(t *testing.T){
+		run_select_exists(t)
+	})
+	t.Run("select not exists", func(t *testing.T){
+		run_select_not_exists(t)
+	})
+	t.Run("select not in subquery", func(t *testing.T){
+		run_select_not_in_subquery(t)
+	})
+	t.Run("delete not exists", func(t *testing.T){
+		run_delete_not_exists(t)
+	})
+	t.Run("validate exists", func(t *testing.T){
+		run_validate_exists(t)
+	})
+}
+
+func run_select_exists(tb testing.TB){
+	query := Select("user").
+		Select([]string{
+			"id",
+			"name",
+		}).
+		Where(Where().
+			Exists_condition(Select("account").
+				Where(Where().
+					Lt("amount", 0),
+				),
+				"user_id", "id",
+			).
+			Eq("name", "test"),
+		)
+	
+	sql, data, _ := query.Compile()
+	
+	want :=
+`SELECT u.id, u.name
+FROM .user u
+WHERE EXISTS (
+SELECT 1
+FROM .account a
+WHERE a.user_id=u.id AND a.amount<?
+) AND u.name=?`
+	got := strings.TrimSpace(sql)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+	if want_data := []any{0, "test"}; !reflect.DeepEqual(want_data, data) {
+		tb.Fatalf("Data want:\n%v\nData got:\n%v", want_data, data)
+	}
+}
+
+func run_select_not_exists(tb testing.TB){
+	query := Select("account").
+		Select([]string{
+			"id",
+		}).
+		Where(Where().
+			Not_exists_condition(Select("account").
+				Where(Where().
+					Gt("amount", 100),
+				),
+				"user_id", "user_id",
+			).
+			Exists(Select("client").
+				Select([]string{
+					"id",
+				}).
+				Where(Where().
+					Eq("active", 1),
+				),
+			),
+		)
+	
+	want :=
+`SELECT a.id
+FROM .account a
+WHERE NOT EXISTS (
+SELECT 1
+FROM .account b
+WHERE b.user_id=a.user_id AND b.amount>100
+) AND EXISTS (
+SELECT id
+FROM .client
+WHERE active=1
+)`
+	got := SQL_debug(query)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+	
+	_, _, err := Select("account").
+		Left_join("user", "u", "id", "user_id").
+		Where(Where().
+			Exists_condition(Select("client").
+				Left_join("user", "u", "client_id", "id"),
+				"id", "u.client_id",
+			),
+		).
+		Compile()
+	if want := "Join table short already used: u (user)"; err == nil || err.Error() != want {
+		tb.Fatalf("Error want:\n%s\nError got:\n%v", want, err)
+	}
+}
+
+func run_select_not_in_subquery(tb testing.TB){
+	query := Select("user").
+		Select([]string{
+			"id",
+		}).
+		Where(Where().
+			Not_in_subquery("id", Select("account").
+				Select([]string{
+					"user_id",
+				}).
+				Where(Where().
+					Gt("amount", 0),
+				),
+			),
+		)
+	
+	want :=
+`SELECT id
+FROM .user
+WHERE id NOT IN (
+SELECT user_id
+FROM .account
+WHERE amount>0
+)`
+	got := SQL_debug(query)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+}
+
+func run_delete_not_exists(tb testing.TB){
+	query := Delete("user").
+		Where(Where().
+			Not_exists_condition(Select("account"), "user_id", "id"),
+		)
+	
+	want :=
+`DELETE u FROM .user u
+WHERE NOT EXISTS (
+SELECT 1
+FROM .account a
+WHERE a.user_id=u.id
+)`
+	got := SQL_debug(query)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+}
+
+func run_validate_exists(tb testing.TB){
+	query := Select("user").
+		Select([]string{
+			"id",
+		}).
+		Where(Where().
+			Exists_condition(Select("account"), "user_idd", "id"),
+		)
+	
+	want := "Unknown column: account.user_idd"
+	err := query.Validate(validate_schema)
+	if err == nil || err.Error() != want {
+		tb.Fatalf("Validate want:\n%s\nValidate got:\n%v", want, err)
+	}
+}
+
+func Benchmark_subquery(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		run_select_subquery(b)
+		run_where_subquery(b)
+	}
+}
+
+func Test_subquery(t *testing.T){
+	t.Run("select subquery", func(t *testing.T){
+		run_select_subquery(t)
+	})
+	t.Run("select subquery uncorrelated", func(t *testing.T){
+		run_select_subquery_uncorrelated(t)
+	})
+	t.Run("where subquery", func(t *testing.T){
+		run_where_subquery(t)
+	})
+	t.Run("where subquery any all", func(t *testing.T){
+		run_where_subquery_any_all(t)
+	})
+	t.Run("validate subquery", func(t *testing.T){
+		run_validate_subquery(t)
+	})
+}
+
+func run_select_subquery(tb testing.TB){
+	query := Select("user").
+		Select([]string{
+			"id",
+			"name",
+		}).
+		Select_subquery_condition("num", Select("account").
+			Select([]string{
+				"count|id",
+			}).
+			Where(Where().
+				Gt("amount", 0),
+			),
+			"user_id", "id",
+		).
+		Where(Where().
+			Eq("name", "test"),
+		)
+	
+	sql, data, _ := query.Compile()
+	
+	want :=
+`SELECT u.id, u.name, (
+SELECT COUNT(a.id)
+FROM .account a
+WHERE a.user_id=u.id AND a.amount>?
+) num
+FROM .user u
+WHERE u.name=?`
+	got := strings.TrimSpace(sql)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+	if want_data := []any{0, "test"}; !reflect.DeepEqual(want_data, data) {
+		tb.Fatalf("Data want:\n%v\nData got:\n%v", want_data, data)
+	}
+}
+
+func run_select_subquery_uncorrelated(tb testing.TB){
+	query := Select("account").
+		Select([]string{
+			"id",
+			"amount",
+		}).
+		Select_subquery("average", Select("account").
+			Select([]string{
+				"avg|amount",
+			}),
+		)
+	
+	want :=
+`SELECT id, amount, (
+SELECT AVG(amount)
+FROM .account
+) average
+FROM .account`
+	got := SQL_debug(query)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+	
+	//	COUNT(*) is not written with a table alias
+	query = Select("user").
+		Select([]string{
+			"id",
+		}).
+		Select_subquery("accounts", Select("account").
+			Select([]string{
+				"count|*",
+			}),
+		).
+		Select_subquery_condition("user_accounts", Select("account").
+			Select([]string{
+				"count|*",
+			}),
+			"user_id", "id",
+		)
+	
+	want =
+`SELECT u.id, (
+SELECT COUNT(*)
+FROM .account
+) accounts, (
+SELECT COUNT(*)
+FROM .account a
+WHERE a.user_id=u.id
+) user_accounts
+FROM .user u`
+	got = SQL_debug(query)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+}
+
+func run_where_subquery(tb testing.TB){
+	query := Select("account").
+		Select([]string{
+			"id",
+		}).
+		Where(Where().
+			Gt_subquery("amount", Select("account").
+				Select([]string{
+					"avg|amount",
+				}).
+				Where(Where().
+					Eq("key", "a"),
+				),
+			).
+			Lt("amount", 1000),
+		)
+	
+	sql, data, _ := query.Compile()
+	
+	want :=
+`SELECT id
+FROM .account
+WHERE amount>(
+SELECT AVG(amount)
+FROM .account
+WHERE key=?
+) AND amount<?`
+	got := strings.TrimSpace(sql)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+	if want_data := []any{"a", 1000}; !reflect.DeepEqual(want_data, data) {
+		tb.Fatalf("Data want:\n%v\nData got:\n%v", want_data, data)
+	}
+}
+
+func run_where_subquery_any_all(tb testing.TB){
+	query := Select("account").
+		Select([]string{
+			"id",
+		}).
+		Where(Where().
+			Any("user_id", Op_eq, Select("user").
+				Select([]string{
+					"id",
+				}).
+				Where(Where().
+					Eq("client_id", 1),
+				),
+			).
+			All("amount", Op_gt, Select("account").
+				Select([]string{
+					"amount",
+				}).
+				Where(Where().
+					Eq("key", "x"),
+				),
+			),
+		)
+	
+	want :=
+`SELECT id
+FROM .account
+WHERE user_id=ANY (
+SELECT id
+FROM .user
+WHERE client_id=1
+) AND amount>ALL (
+SELECT amount
+FROM .account
+WHERE key=x
+)`
+	got := SQL_debug(query)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+	
+	_, _, err := Select("account").
+		Where(Where().Any("user_id", Op_in, Select("user"))).
+		Compile()
+	if want := "Invalid subquery operator"; err == nil || err.Error() != want {
+		tb.Fatalf("Error want:\n%s\nError got:\n%v", want, err)
+	}
+}
+
+func run_validate_subquery(tb testing.TB){
+	query := Select("user").
+		Select([]string{
+			"id",
+		}).
+		Select_subquery_condition("num", Select("account").
+			Select([]string{
+				"count|id",
+			}),
+			"usr_id", "id",
+		).
+		Where(Where().
+			Gt_subquery("id", Select("account").
+				Select([]string{
+					"max|user_idd",
+				}),
+			),
+		)
+	
+	want := "Unknown column: account.usr_id\nUnknown column: account.user_idd"
+	err := query.Validate(validate_schema)
+	if err == nil || err.Error() != want {
+		tb.Fatalf("Validate want:\n%s\nValidate got:\n%v", want, err)
+	}
+}
+
+func Benchmark_where_pattern(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		run_where_like(b)
+		run_where_contains(b)
+	}
+}
+
+func Test_where_pattern(t *testing.T){
+	t.Run("where like", func(t *testing.T){
+		run_where_like(t)
+	})
+	t.Run("where contains", func(t *testing.T){
+		run_where_contains(t)
+	})
+	t.Run("where pattern operator compatibility", func(t *testing.T){
+		run_where_pattern_compatibility(t)
+	})
+}
+
+func run_where_like(tb testing.TB){
+	query := Select("user").
+		Select([]string{
+			"id",
+		}).
+		Where(Where().
+			Like("name", "a%").
+			Not_like("name", "ab%").
+			Regexp("email", "^[a-z]+@").
+			Not_regexp("email", "@test\\.com$").
+			Not_null("email"),
+		)
+	
+	sql, data, _ := query.Compile()
+	
+	want :=
+`SELECT id
+FROM .user
+WHERE name LIKE ? AND name NOT LIKE ? AND email REGEXP ? AND email NOT REGEXP ? AND email IS NOT NULL`
+	got := strings.TrimSpace(sql)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+	if want_data := []any{"a%", "ab%", "^[a-z]+@", "@test\\.com$"}; !reflect.DeepEqual(want_data, data) {
+		tb.Fatalf("Data want:\n%v\nData got:\n%v", want_data, data)
+	}
+}
+
+func run_where_contains(tb testing.TB){
+	query := Select("user").
+		Select([]string{
+			"id",
+		}).
+		Left_join("client", "c", "id", "client_id").
+		Where(Where().
+			Contains("name", "50%_off!").
+			Starts_with("c.timeout", "a\\b").
+			Ends_with("email", "@test.com"),
+		)
+	
+	sql, data, _ := query.Compile()
+	
+	want :=
+`SELECT u.id
+FROM .user u
+LEFT JOIN .client c ON c.id=u.client_id
+WHERE u.name LIKE ? ESCAPE '!' AND c.timeout LIKE ? ESCAPE '!' AND u.email LIKE ? ESCAPE '!'`
+	got := strings.TrimSpace(sql)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+	if want_data := []any{"%50!%!_off!!%", "a\\b%", "%@test.com"}; !reflect.DeepEqual(want_data, data) {
+		tb.Fatalf("Data want:\n%v\nData got:\n%v", want_data, data)
+	}
+}
+
+func run_where_pattern_compatibility(tb testing.TB){
+	_, _, err := Select("user").
+		Where(Where().
+			Contains("name", "a").
+			Contains("name", "b").
+			Not_like("name", "c%"),
+		).
+		Compile()
+	if err != nil {
+		tb.Fatalf("SQL want: nil\nSQL got:\n%v", err)
+	}
+	
+	_, _, err = Select("user").
+		Where(Where().
+			Like("name", "a%").
+			Eq("name", "b"),
+		).
+		Compile()
+	if want := "Where clause operator incompatable on same field (name): LIKE ="; err == nil || err.Error() != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%v", want, err)
+	}
+	
+	_, _, err = Select("user").
+		Where(Where().
+			Null("name").
+			Regexp("name", "^a"),
+		).
+		Compile()
+	if want := "Where clause operator incompatable on same field (name): IS NULL REGEXP"; err == nil || err.Error() != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%v", want, err)
+	}
+}
+
+func Benchmark_match(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		run_select_match(b)
+		run_match_boolean(b)
+	}
+}
+
+func Test_match(t *testing.T){
+	t.Run("select match", func(t *testing.T){
+		run_select_match(t)
+	})
+	t.Run("match boolean query", func(t *testing.T){
+		run_match_boolean(t)
+	})
+	t.Run("validate match", func(t *testing.T){
+		run_validate_match(t)
+	})
+}
+
+func run_select_match(tb testing.TB){
+	search := Match_boolean().
+		Must("red shoe").
+		String()
+	
+	query := Select("user").
+		Select([]string{
+			"id",
+		}).
+		Select_match("score", []string{"name", "c.timeout"}, search, Mode_boolean).
+		Left_join("client", "c", "id", "client_id").
+		Where(Where().
+			Match([]string{"name", "c.timeout"}, search, Mode_boolean).
+			Eq("c.active", 1),
+		).
+		Order([]string{
+			"id",
+		}).
+		Order_relevance("score")
+	
+	sql, data, _ := query.Compile()
+	
+	want :=
+`SELECT u.id, MATCH(u.name, c.timeout) AGAINST (? IN BOOLEAN MODE) score
+FROM .user u
+LEFT JOIN .client c ON c.id=u.client_id
+WHERE MATCH(u.name, c.timeout) AGAINST (? IN BOOLEAN MODE) AND c.active=?
+ORDER BY score DESC, u.id`
+	got := strings.TrimSpace(sql)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+	if want_data := []any{"+red +shoe", "+red +shoe", 1}; !reflect.DeepEqual(want_data, data) {
+		tb.Fatalf("Data want:\n%v\nData got:\n%v", want_data, data)
+	}
+	
+	//	Order fields named as a select alias are prefixed unless ordered by relevance
+	query = Select("user").
+		Select([]string{
+			"c.timeout=name",
+		}).
+		Left_join("client", "c", "id", "client_id").
+		Order([]string{
+			"name",
+		})
+	
+	want =
+`SELECT c.timeout name
+FROM .user u
+LEFT JOIN .client c ON c.id=u.client_id
+ORDER BY u.name`
+	got = SQL_debug(query)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+	
+	query = Select("user").
+		Select([]string{
+			"id",
+		}).
+		Where(Where().
+			Match([]string{"name"}, "red shoes", Mode_natural).
+			Match([]string{"email"}, "test", Mode_query_expansion),
+		)
+	
+	want =
+`SELECT id
+FROM .user
+WHERE MATCH(name) AGAINST (red shoes IN NATURAL LANGUAGE MODE) AND MATCH(email) AGAINST (test WITH QUERY EXPANSION)`
+	got = SQL_debug(query)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+}
+
+func run_match_boolean(tb testing.TB){
+	got := Match_boolean().
+		Must("red +shoe").
+		Must_not("-blue (x)").
+		Should("size>42").
+		Prefix("sneak*").
+		Phrase(`a "quoted" ~phrase`).
+		Phrase(`"`).
+		String()
+	
+	want := `+red +shoe -blue -x size 42 sneak* +"a quoted phrase"`
+	if got != want {
+		tb.Fatalf("Query want:\n%s\nQuery got:\n%s", want, got)
+	}
+}
+
+func run_validate_match(tb testing.TB){
+	query := Select("user").
+		Select_match("score", []string{"nam"}, "test", Mode_natural).
+		Where(Where().
+			Match([]string{"emial"}, "test", Mode_natural),
+		)
+	
+	want := "Unknown column: user.nam\nUnknown column: user.emial"
+	err := query.Validate(validate_schema)
+	if err == nil || err.Error() != want {
+		tb.Fatalf("Validate want:\n%s\nValidate got:\n%v", want, err)
+	}
+}
+
+func Benchmark_where_col(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		run_where_col(b)
+		run_join_col(b)
+	}
+}
+
+func Test_where_col(t *testing.T){
+	t.Run("where col", func(t *testing.T){
+		run_where_col(t)
+	})
+	t.Run("join col", func(t *testing.T){
+		run_join_col(t)
+	})
+	t.Run("having col", func(t *testing.T){
+		run_having_col(t)
+	})
+	t.Run("validate col", func(t *testing.T){
+		run_validate_col(t)
+	})
+}
+
+func run_where_col(tb testing.TB){
+	query := Select("user").
+		Select([]string{
+			"id",
+		}).
+		Left_join("client", "c", "id", "client_id").
+		Left_join("account", "x", "user_id", "id").
+		Where(Where().
+			Gt("time", Col("c.timeout")).
+			Not_eq("id", Col("client_id")).
+			Eq("name", "test"),
+		).
+		Optimize_joins()
+	
+	sql, data, _ := query.Compile()
+	
+	want :=
+`SELECT u.id
+FROM .user u
+LEFT JOIN .client c ON c.id=u.client_id
+WHERE u.time>c.timeout AND u.id!=u.client_id AND u.name=?`
+	got := strings.TrimSpace(sql)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+	if want_data := []any{"test"}; !reflect.DeepEqual(want_data, data) {
+		tb.Fatalf("Data want:\n%v\nData got:\n%v", want_data, data)
+	}
+}
+
+func run_join_col(tb testing.TB){
+	query := Select("user").
+		Select([]string{
+			"id",
+			"x.amount",
+		}).
+		Left_join_multi("account", "x", Join_conditions{{
+			Field:			"user_id",
+			Field_foreign:	"id",
+		},{
+			Field:			"key",
+			Fixed_value:	true,
+			Operator:		Op_eq,
+			Field_value:	Col("c.id"),
+		}}).
+		Left_join("client", "c", "id", "client_id").
+		Optimize_joins()
+	
+	want :=
+`SELECT u.id, x.amount
+FROM .user u
+LEFT JOIN .client c ON c.id=u.client_id
+LEFT JOIN .account x ON x.user_id=u.id AND x.key=c.id`
+	got := SQL_debug(query)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+	
+	_, _, err := Select("user").
+		Left_join_multi("account", "x", Join_conditions{{
+			Field:			"key",
+			Fixed_value:	true,
+			Operator:		Op_like,
+			Field_value:	Col("name"),
+		}}).
+		Compile()
+	if want := "Invalid operator for field comparison: name"; err == nil || err.Error() != want {
+		tb.Fatalf("Error want:\n%s\nError got:\n%v", want, err)
+	}
+}
+
+func run_having_col(tb testing.TB){
+	query := Select("account").
+		Select([]string{
+			"user_id",
+			"c.timeout=limit_amount",
+			"sum|amount=total",
+		}).
+		Left_join("client", "c", "id", "user_id").
+		Group([]string{
+			"user_id",
+		}).
+		Having(Where().
+			Gt("total", Col("limit_amount")).
+			Lt("max|amount", Col("sum|amount")),
+		)
+	
+	want :=
+`SELECT a.user_id, c.timeout limit_amount, SUM(a.amount) total
+FROM .account a
+LEFT JOIN .client c ON c.id=a.user_id
+GROUP BY a.user_id
+HAVING total>limit_amount AND MAX(a.amount)<SUM(a.amount)`
+	got := SQL_debug(query)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+	if err := query.Validate(validate_schema); err != nil {
+		tb.Fatalf("Validate want: <nil>\nValidate got:\n%v", err)
+	}
+}
+
+func run_validate_col(tb testing.TB){
+	query := Select("user").
+		Select([]string{
+			"id",
+		}).
+		Where(Where().
+			Gt("time", Col("tim")),
+		)
+	
+	want := "Unknown column: user.tim"
+	err := query.Validate(validate_schema)
+	if err == nil || err.Error() != want {
+		tb.Fatalf("Validate want:\n%s\nValidate got:\n%v", want, err)
+	}
+}
+
+func Benchmark_where_tree(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		run_where_tree(b)
+	}
+}
+
+func Test_where_tree(t *testing.T){
+	t.Run("where tree", func(t *testing.T){
+		run_where_tree(t)
+	})
+	t.Run("where not", func(t *testing.T){
+		run_where_not(t)
+	})
+	t.Run("where or group nested", func(t *testing.T){
+		run_where_or_group_nested(t)
+	})
+	t.Run("where tree duplicates", func(t *testing.T){
+		run_where_tree_duplicates(t)
+	})
+	t.Run("validate tree", func(t *testing.T){
+		run_validate_tree(t)
+	})
+}
+
+func run_where_tree(tb testing.TB){
+	query := Select("user").
+		Select([]string{
+			"id",
+		}).
+		Left_join("client", "c", "id", "client_id").
+		Left_join("account", "x", "user_id", "id").
+		Where(And(
+			Where().Eq("name", "a"),
+			Or(
+				Where().Eq("email", "b"),
+				Where().Eq("c.active", 1).Gt("time", 2),
+			),
+		)).
+		Optimize_joins()
+	
+	sql, data, _ := query.Compile()
+	
+	want :=
+`SELECT u.id
+FROM .user u
+LEFT JOIN .client c ON c.id=u.client_id
+WHERE u.name=? AND (u.email=? OR (c.active=? AND u.time>?))`
+	got := strings.TrimSpace(sql)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+	if want_data := []any{"a", "b", 1, 2}; !reflect.DeepEqual(want_data, data) {
+		tb.Fatalf("Data want:\n%v\nData got:\n%v", want_data, data)
+	}
+	
+	//	Top level OR is wrapped in parentheses with the id condition
+	want =
+`SELECT id
+FROM .user
+WHERE id=1 AND (name=a OR email=b)`
+	got = SQL_debug(Select_id("user", 1).
+		Select([]string{
+			"id",
+		}).
+		Where(Or(
+			Where().Eq("name", "a"),
+			Where().Eq("email", "b"),
+		)),
+	)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+}
+
+func run_where_not(tb testing.TB){
+	query := Select("user").
+		Select([]string{
+			"id",
+		}).
+		Where(Where().
+			Eq("id", 1).
+			Not(Where().Eq("name", "a").Eq("email", "b")).
+			Not(Or(
+				Where().Eq("client_id", 2),
+				Where().Null("time"),
+			)).
+			Not(Where().Eq("email", "c")),
+		)
+	
+	want :=
+`SELECT id
+FROM .user
+WHERE id=1 AND NOT (name=a AND email=b) AND NOT (client_id=2 OR time IS NULL) AND NOT (email=c)`
+	got := SQL_debug(query)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+	
+	//	Not() node as or group
+	query = Select("user").
+		Select([]string{
+			"id",
+		}).
+		Where(Where().
+			Eq("client_id", 0).
+			Or_group(Not(Where().Eq("name", "a").Eq("email", "b"))),
+		)
+	
+	want =
+`SELECT id
+FROM .user
+WHERE client_id=0 AND NOT (name=a AND email=b)`
+	got = SQL_debug(query)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+}
+
+func run_where_or_group_nested(tb testing.TB){
+	group := Where().
+		Eq("name", "a").
+		Wrap(Where().Eq("email", "b").Gt("time", 3)).
+		Or_group(Where().Eq("id", 1).Eq("id", 2))
+	
+	query := Select("user").
+		Select([]string{
+			"id",
+		}).
+		Where(Where().
+			Eq("client_id", 5).
+			Or_group(group),
+		)
+	
+	want :=
+`SELECT id
+FROM .user
+WHERE client_id=5 AND ((email=b AND time>3) OR name=a OR id=1 OR id=2)`
+	got := SQL_debug(query)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+}
+
+func run_where_tree_duplicates(tb testing.TB){
+	//	Same field in different branches of OR
+	_, _, err := Select("user").
+		Where(Where().
+			Eq("id", 1).
+			Or(
+				Where().Eq("name", "a"),
+				Where().Eq("name", "b").Eq("id", 2),
+			),
+		).
+		Compile()
+	if err != nil {
+		tb.Fatalf("Error want: nil\nError got:\n%v", err)
+	}
+	
+	//	Same field ANDed across nodes
+	_, _, err = Select("user").
+		Where(And(
+			Where().Eq("id", 1),
+			And(Where().Eq("id", 2)),
+		)).
+		Compile()
+	if want := "Where clause operator incompatable on same field (id): = ="; err == nil || err.Error() != want {
+		tb.Fatalf("Error want:\n%s\nError got:\n%v", want, err)
+	}
+}
+
+func run_validate_tree(tb testing.TB){
+	query := Select("user").
+		Select([]string{
+			"id",
+		}).
+		Where(Or(
+			Where().Eq("name", "a"),
+			Not(And(Where().Eq("mail", "b"))),
+		))
+	
+	want := "Unknown column: user.mail"
+	err := query.Validate(validate_schema)
+	if err == nil || err.Error() != want {
+		tb.Fatalf("Validate want:\n%s\nValidate got:\n%v", want, err)
+	}
+}
+
+func Benchmark_where_raw(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		run_where_raw(b)
+	}
+}
+
+func Test_where_raw(t *testing.T){
+	t.Run("where raw", func(t *testing.T){
+		run_where_raw(t)
+	})
+	t.Run("where raw error", func(t *testing.T){
+		run_where_raw_error(t)
+	})
+}
+
+func run_where_raw(tb testing.TB){
+	query := Select("user").
+		Select([]string{
+			"id",
+		}).
+		Left_join("client", "c", "id", "client_id").
+		Left_join("account", "x", "user_id", "id").
+		Where(Where().
+			Eq("name", "a").
+			Raw("DATE(<root>.time) = ? AND c.active & ? > 0", "2024-01-01", 4).
+			Eq("email", "b"),
+		).
+		Optimize_joins()
+	
+	sql, data, _ := query.Compile()
+	
+	want :=
+`SELECT u.id
+FROM .user u
+LEFT JOIN .client c ON c.id=u.client_id
+WHERE u.name=? AND (DATE(u.time) = ? AND c.active & ? > 0) AND u.email=?`
+	got := strings.TrimSpace(sql)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+	if want_data := []any{"a", "2024-01-01", 4, "b"}; !reflect.DeepEqual(want_data, data) {
+		tb.Fatalf("Data want:\n%v\nData got:\n%v", want_data, data)
+	}
+	
+	//	Without table aliases
+	want =
+`SELECT id
+FROM .user
+WHERE (id=1 OR (DATE(time) = 2024-01-01))`
+	got = SQL_debug(Select("user").
+		Select([]string{
+			"id",
+		}).
+		Where(Or(
+			Where().Eq("id", 1),
+			Where().Raw("DATE(<root>.time) = ?", "2024-01-01"),
+		)),
+	)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+}
+
+func run_where_raw_error(tb testing.TB){
+	_, _, err := Select("user").
+		Where(Where().Raw("id = ? OR id = ?", 1)).
+		Compile()
+	if want := "Raw where condition has 2 placeholders and 1 args: id = ? OR id = ?"; err == nil || err.Error() != want {
+		tb.Fatalf("Error want:\n%s\nError got:\n%v", want, err)
+	}
+	
+	//	Quoted question marks are not placeholders, slice args are expanded and []byte is bound as one value
+	sql, data, err := Select("user").
+		Select([]string{
+			"id",
+		}).
+		Where(Where().Raw(`name LIKE '%?%' AND email != 'it\'s?' AND id IN (?) AND client_id IN (?) AND FIND_IN_SET(?, "a?b") AND time=?`, []any{1, 2}, []uint64{3}, "a", []byte("b"))).
+		Compile()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	want :=
+`SELECT id
+FROM .user
+WHERE (name LIKE '%?%' AND email != 'it\'s?' AND id IN (?, ?) AND client_id IN (?) AND FIND_IN_SET(?, "a?b") AND time=?)`
+	if got := strings.TrimSpace(sql); got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+	if want_data := []any{1, 2, uint64(3), "a", []byte("b")}; !reflect.DeepEqual(want_data, data) {
+		tb.Fatalf("Data want:\n%v\nData got:\n%v", want_data, data)
+	}
+	
+	_, _, err = Select("user").
+		Where(Where().Raw("id IN (?)", []any{})).
+		Compile()
+	if want := "Raw where condition arg 1 is an empty list: id IN (?)"; err == nil || err.Error() != want {
+		tb.Fatalf("Error want:\n%s\nError got:\n%v", want, err)
+	}
+}
+
+func Benchmark_select_lock(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		run_select_lock(b)
+	}
+}
+
+func Test_select_lock(t *testing.T){
+	t.Run("select lock", func(t *testing.T){
+		run_select_lock(t)
+	})
+	t.Run("select lock error", func(t *testing.T){
+		run_select_lock_error(t)
+	})
+}
+
+func run_select_lock(tb testing.TB){
+	tests := []struct{
+		query	*Select_query
+		want	string
+	}{{
+		Select_id("user", 1).Select([]string{"id"}).Lock_for_share(),
+		"FOR SHARE",
+	},{
+		Select_id("user", 1).Select([]string{"id"}).Lock_in_share_mode(),
+		"LOCK IN SHARE MODE",
+	},{
+		Select_id("user", 1).Select([]string{"id"}).Lock_for_update().Lock_nowait(),
+		"FOR UPDATE NOWAIT",
+	},{
+		Select_id("user", 1).Select([]string{"id"}).Lock_for_update().Lock_skip_locked(),
+		"FOR UPDATE SKIP LOCKED",
+	},{
+		Select_id("user", 1).Select([]string{"id"}).Lock_for_share().Lock_wait(5),
+		"FOR SHARE WAIT 5",
+	},{
+		Select_id("user", 1).Select([]string{"id"}).Lock_for_update().Lock_of("user"),
+		"FOR UPDATE OF user",
+	}}
+	for _, test := range tests {
+		want := "SELECT id\nFROM .user\nWHERE id=1\n"+test.want
+		if got := SQL_debug(test.query); got != want {
+			tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+		}
+	}
+	
+	//	Lock table join is kept with optimized joins
+	query := Select("user").
+		Select([]string{
+			"id",
+		}).
+		Left_join("client", "c", "id", "client_id").
+		Left_join("account", "x", "user_id", "id").
+		Lock_for_update().
+		Lock_of("user", "c").
+		Lock_skip_locked().
+		Optimize_joins()
+	
+	want :=
+`SELECT u.id
+FROM .user u
+LEFT JOIN .client c ON c.id=u.client_id
+FOR UPDATE OF u, c SKIP LOCKED`
+	if got := SQL_debug(query); got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+}
+
+func run_select_lock_error(tb testing.TB){
+	tests := []struct{
+		query	*Select_query
+		want	string
+	}{{
+		Select("user").Lock_nowait(),
+		"Lock options require FOR UPDATE or FOR SHARE",
+	},{
+		Select("user").Lock_in_share_mode().Lock_skip_locked(),
+		"Lock options are not supported with LOCK IN SHARE MODE",
+	},{
+		Select("user").Lock_for_update().Lock_of("c"),
+		"Unknown lock table: c",
+	}}
+	for _, test := range tests {
+		if _, _, err := test.query.Compile(); err == nil || err.Error() != test.want {
+			tb.Fatalf("Error want:\n%s\nError got:\n%v", test.want, err)
+		}
+	}
+}
+
+func Benchmark_hints(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		run_select_hints(b)
+	}
+}
+
+func Test_hints(t *testing.T){
+	t.Run("select hints", func(t *testing.T){
+		run_select_hints(t)
+	})
+	t.Run("update delete hints", func(t *testing.T){
+		run_update_delete_hints(t)
+	})
+	t.Run("hint error", func(t *testing.T){
+		run_hint_error(t)
+	})
+}
+
+func run_select_hints(tb testing.TB){
+	query := Select("user").
+		Select_distinct([]string{
+			"id",
+			"c.timeout",
+		}).
+		Left_join("client", "c", "id", "client_id").
+		Force_index("idx_time").
+		Ignore_index("idx_name", "idx_email").
+		Join_index("c", Index_use, "PRIMARY").
+		Max_execution_time(1000).
+		Optimizer_hint("BKA(c)").
+		Straight_join().
+		Where(Where().Gt("time", 10))
+	
+	want :=
+`SELECT /*+ MAX_EXECUTION_TIME(1000) BKA(c) */ DISTINCT STRAIGHT_JOIN u.id, c.timeout
+FROM .user u FORCE INDEX (idx_time) IGNORE INDEX (idx_name, idx_email)
+LEFT JOIN .client c USE INDEX (PRIMARY) ON c.id=u.client_id
+WHERE u.time>10`
+	if got := SQL_debug(query); got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+	
+	want =
+`SELECT id
+FROM .user USE INDEX (idx_time)
+WHERE time>10`
+	got := SQL_debug(Select("user").
+		Select([]string{
+			"id",
+		}).
+		Use_index("idx_time").
+		Where(Where().Gt("time", 10)),
+	)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+}
+
+func run_update_delete_hints(tb testing.TB){
+	want :=
+`UPDATE /*+ NO_RANGE_OPTIMIZATION(user) */ .user FORCE INDEX (idx_time)
+SET name=test
+WHERE time<10`
+	got := SQL_debug(Update("user").
+		Fields(map[string]any{
+			"name":	"test",
+		}).
+		Force_index("idx_time").
+		Optimizer_hint("NO_RANGE_OPTIMIZATION(user)").
+		Where(Where().Lt("time", 10)),
+	)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+	
+	want =
+`DELETE u FROM .user u USE INDEX (idx_time)
+WHERE u.time<10`
+	got = SQL_debug(Delete("user").
+		Use_index("idx_time").
+		Where(Where().Lt("time", 10)),
+	)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+}
+
+func run_hint_error(tb testing.TB){
+	tests := []struct{
+		query	*Select_query
+		want	string
+	}{{
+		Select("user").Use_index(),
+		"Index hint without indexes: USE INDEX",
+	},{
+		Select("user").Join_index("c", Index_force, "PRIMARY"),
+		"Unknown join table alias for index hint: c",
+	},{
+		Select("user").Left_join_subquery(Select("client"), "c", Join_conditions{{Field: "id", Field_foreign: "client_id"}}).Join_index("c", Index_use, "PRIMARY"),
+		"Index hint on join subquery: c",
+	},{
+		Select("user").Optimizer_hint("BKA(c) */ DROP"),
+		"Invalid optimizer hint: BKA(c) */ DROP",
+	}}
+	for _, test := range tests {
+		if _, _, err := test.query.Compile(); err == nil || err.Error() != test.want {
+			tb.Fatalf("Error want:\n%s\nError got:\n%v", test.want, err)
+		}
+	}
+}
+
+func Benchmark_select_json_agg(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		run_select_json_nested(b)
+	}
+}
+
+func Test_select_json_agg(t *testing.T){
+	t.Run("select json nested", func(t *testing.T){
+		run_select_json_nested(t)
+	})
+	t.Run("select json object", func(t *testing.T){
+		run_select_json_object(t)
+	})
+	t.Run("select json error", func(t *testing.T){
+		run_select_json_agg_error(t)
+	})
+	t.Run("validate json agg", func(t *testing.T){
+		run_validate_json_agg(t)
+	})
+}
+
+func run_select_json_nested(tb testing.TB){
+	accounts := Select("account").
+		Select([]string{
+			"id",
+			"amount",
+		}).
+		Select_json_agg("client", Json_object(Select("client").
+			Select([]string{
+				"timeout",
+			}),
+		).Condition("id", "<root>.client_id"))
+	
+	query := Select("user").
+		Select([]string{
+			"id",
+		}).
+		Select_json_agg("accounts", Json_array(accounts).
+			Condition("user_id", "id").
+			Order("amount DESC").
+			Empty(),
+		).
+		Where(Where().
+			Eq("name", "test"),
+		)
+	
+	sql, data, _ := query.Compile()
+	
+	want :=
+`SELECT u.id,
+COALESCE((
+SELECT JSON_ARRAYAGG(JSON_OBJECT('id', a.id, 'amount', a.amount, 'client', (
+SELECT JSON_OBJECT('timeout', c.timeout)
+FROM .client c
+WHERE c.id=u.client_id
+LIMIT 1
+)) ORDER BY a.amount DESC)
+FROM .account a
+WHERE a.user_id=u.id
+), JSON_ARRAY()) accounts
+FROM .user u
+WHERE u.name=?`
+	got := strings.TrimSpace(sql)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+	if want_data := []any{"test"}; !reflect.DeepEqual(want_data, data) {
+		tb.Fatalf("Data want:\n%v\nData got:\n%v", want_data, data)
+	}
+}
+
+func run_select_json_object(tb testing.TB){
+	query := Select("client").
+		Select([]string{
+			"id",
+		}).
+		Select_json_agg("users", Json_objectagg("email", Select("user").
+			Select([]string{
+				"name",
+			}).
+			Where(Where().
+				Gt("time", 10),
+			),
+		).Condition("client_id", "id").Empty()).
+		Select_json_agg("last_user", Json_object(Select("user").
+			Select([]string{
+				"name",
+			}),
+		).Condition("client_id", "id").Order("time DESC"))
+	
+	want :=
+`SELECT c.id,
+COALESCE((
+SELECT JSON_OBJECTAGG(u.email, JSON_OBJECT('name', u.name))
+FROM .user u
+WHERE u.client_id=c.id AND u.time>10
+), JSON_OBJECT()) users,
+(
+SELECT JSON_OBJECT('name', a.name)
+FROM .user a
+WHERE a.client_id=c.id
+ORDER BY a.time DESC
+LIMIT 1
+) last_user
+FROM .client c`
+	if got := SQL_debug(query); got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+}
+
+func run_select_json_agg_error(tb testing.TB){
+	tests := []struct{
+		query	*Select_query
+		want	string
+	}{{
+		Select("user").Select([]string{"id"}).Select_json_agg("accounts", Json_array(Select("account"))),
+		"Select json without fields: accounts",
+	},{
+		Select("user").Select([]string{"id"}).Select_json_agg("accounts", Json_objectagg("key", Select("account").Select([]string{"amount"})).Order("id")),
+		"Select json order is not supported with JSON_OBJECTAGG: accounts",
+	}}
+	for _, test := range tests {
+		if _, _, err := test.query.Compile(); err == nil || err.Error() != test.want {
+			tb.Fatalf("Error want:\n%s\nError got:\n%v", test.want, err)
+		}
+	}
+}
+
+func run_validate_json_agg(tb testing.TB){
+	query := Select("user").
+		Select([]string{
+			"id",
+		}).
+		Select_json_agg("accounts", Json_objectagg("kye", Select("account").
+			Select([]string{
+				"amount",
+			}).
+			Select_json_agg("client", Json_object(Select("client").
+				Select([]string{
+					"timeuot",
+				}),
+			)),
+		).Condition("user_id", "id"))
+	
+	want :=
+`Unknown column: client.timeuot
+Unknown column: account.kye`
+	err := query.Validate(validate_schema)
+	if err == nil || err.Error() != want {
+		tb.Fatalf("Validate want:\n%s\nValidate got:\n%v", want, err)
+	}
+}
+
+func Benchmark_json_path(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		run_json_path_select(b)
+	}
+}
+
+func Test_json_path(t *testing.T){
+	t.Run("json path select", func(t *testing.T){
+		run_json_path_select(t)
+	})
+	t.Run("json path update", func(t *testing.T){
+		run_json_path_update(t)
+	})
+	t.Run("json path error", func(t *testing.T){
+		run_json_path_error(t)
+	})
+	t.Run("json path syntax", func(t *testing.T){
+		run_json_path_syntax(t)
+	})
+}
+
+func run_json_path_select(tb testing.TB){
+	query := Select("user").
+		Select([]string{
+			"id",
+		}).
+		Select_json_value("theme", "settings", "$.theme").
+		Select_json_extract("tags", "c.settings", "$.tags").
+		Left_join("client", "c", "id", "client_id").
+		Where(Where().
+			Json_eq("settings", "$.theme", "dark").
+			Json_eq("settings", "$.enabled", true).
+			Json_eq("settings", "$.level", 2).
+			Json_eq("settings", "$.deleted", nil).
+			Json_contains("settings", "$.roles", "admin").
+			Json_overlaps("c.settings", "$.tags[*]", []string{"a", "b"}),
+		)
+	
+	sql, data, _ := query.Compile()
+	
+	want :=
+`SELECT u.id, JSON_UNQUOTE(JSON_EXTRACT(u.settings, '$.theme')) theme, JSON_EXTRACT(c.settings, '$.tags') tags
+FROM .user u
+LEFT JOIN .client c ON c.id=u.client_id
+WHERE JSON_UNQUOTE(JSON_EXTRACT(u.settings, '$.theme'))=? AND JSON_EXTRACT(u.settings, '$.enabled')=JSON_EXTRACT(?, '$') AND JSON_EXTRACT(u.settings, '$.level')=? AND JSON_EXTRACT(u.settings, '$.deleted')=JSON_EXTRACT('null', '$') AND JSON_CONTAINS(u.settings, ?, '$.roles') AND JSON_OVERLAPS(JSON_EXTRACT(c.settings, '$.tags[*]'), ?)`
+	got := strings.TrimSpace(sql)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+	want_data := []any{"dark", "true", 2, `"admin"`, `["a","b"]`}
+	if !reflect.DeepEqual(want_data, data) {
+		tb.Fatalf("Data want:\n%v\nData got:\n%v", want_data, data)
+	}
+}
+
+func run_json_path_update(tb testing.TB){
+	query := Update_id("user", 1).
+		Fields_operator(Fields().
+			Value("name", "test").
+			Json_set("settings", "$.theme", "dark").
+			Json_set("settings", `$."font size"`, 12).
+			Json_remove("settings", "$.old").
+			Json_array_append("settings", "$.tags", map[string]any{"id": 1}),
+		)
+	
+	sql, data, _ := query.Compile()
+	
+	want :=
+`UPDATE .user
+SET name=?, settings=JSON_ARRAY_APPEND(JSON_REMOVE(JSON_SET(settings, '$.theme', ?, '$."font size"', ?), '$.old'), '$.tags', JSON_EXTRACT(?, '$'))
+WHERE id=?`
+	got := strings.TrimSpace(sql)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+	want_data := []any{"test", "dark", 12, `{"id":1}`, uint64(1)}
+	if !reflect.DeepEqual(want_data, data) {
+		tb.Fatalf("Data want:\n%v\nData got:\n%v", want_data, data)
+	}
+}
+
+func run_json_path_error(tb testing.TB){
+	tests := []struct{
+		query	SQL
+		want	string
+	}{{
+		Select("user").Select_json_value("theme", "settings", "theme"),
+		"Invalid JSON path: theme",
+	},{
+		Select("user").Where(Where().Json_contains("settings", "$.roles[*]", "admin")),
+		"Invalid JSON path: $.roles[*]",
+	},{
+		Update_id("user", 1).Fields_operator(Fields().Json_set("settings", "$.a', 1)", 1)),
+		"Invalid JSON path: $.a', 1)",
+	},{
+		Insert("user").Update_duplicate_operator(Fields().Json_set("settings", "$.a", 1), nil),
+		"JSON path edits are only valid in updates: settings",
+	}}
+	for _, test := range tests {
+		if _, _, err := test.query.Compile(); err == nil || err.Error() != test.want {
+			tb.Fatalf("Error want:\n%s\nError got:\n%v", test.want, err)
+		}
+	}
+}
+
+func run_json_path_syntax(tb testing.TB){
+	for path, want := range map[string]bool{
+		"$":				true,
+		"$.a.b_c":			true,
+		`$."a b"`:			true,
+		"$.a[0][12]":		true,
+		"$.*":				true,
+		"$[*].a":			true,
+		"":					false,
+		"a":				false,
+		"$.":				false,
+		"$..a":				false,
+		`$.""`:				false,
+		`$."a`:				false,
+		"$[]":				false,
+		"$[a]":				false,
+		"$.a b":			false,
+		"$.a'":				false,
+		`$."a'b"`:			false,
+		`$."a\"`:			false,
+	} {
+		if got := valid_json_path(path, true); got != want {
+			tb.Fatalf("Valid JSON path %q want: %t got: %t", path, want, got)
+		}
+	}
+	if valid_json_path("$.a[*]", false) {
+		tb.Fatalf("Valid JSON path %q want: false got: true", "$.a[*]")
+	}
+}
+func Benchmark_set_operations(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		run_set_precedence(b)
+	}
+}
+
+func Test_set_operations(t *testing.T){
+	t.Run("set precedence", func(t *testing.T){
+		run_set_precedence(t)
+	})
+	t.Run("set member order limit", func(t *testing.T){
+		run_set_member_limit(t)
+	})
+	t.Run("set nested", func(t *testing.T){
+		run_set_nested(t)
+	})
+	t.Run("set validate", func(t *testing.T){
+		run_set_validate(t)
+	})
+}
+
+func run_set_precedence(tb testing.TB){
+	query := Union().
+		Union(Select("account").
+			Select([]string{
+				"user_id",
+			}).
+			Where(Where().
+				Eq("key", "a"),
+			),
+		).
+		Union(Select("account").
+			Select([]string{
+				"user_id",
+			}).
+			Where(Where().
+				Eq("key", "b"),
+			),
+		).
+		Intersect(Select("user").
+			Select([]string{
+				"id user_id",
+			}),
+		).
+		Except(Select("account").
+			Select([]string{
+				"user_id",
+			}).
+			Where(Where().
+				Eq("key", "c"),
+			),
+		).
+		Intersect_all(Select("user").
+			Select([]string{
+				"id user_id",
+			}).
+			Where(Where().
+				Eq("client_id", 1),
+			),
+		).
+		Order([]string{
+			"user_id",
+		})
+	
+	sql, data, err := query.Compile()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	
+	want :=
+`(
+(
+SELECT user_id
+FROM .account
+WHERE key=?
+UNION
+SELECT user_id
+FROM .account
+WHERE key=?
+)
+INTERSECT
+SELECT id user_id
+FROM .user
+EXCEPT
+SELECT user_id
+FROM .account
+WHERE key=?
+)
+INTERSECT ALL
+SELECT id user_id
+FROM .user
+WHERE client_id=?
+ORDER BY user_id`
+	got := strings.TrimSpace(sql)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+	want_data := []any{"a", "b", "c", 1}
+	if !reflect.DeepEqual(want_data, data) {
+		tb.Fatalf("Data want:\n%v\nData got:\n%v", want_data, data)
+	}
+}
+
+func run_set_member_limit(tb testing.TB){
+	query := Union_all().
+		Select([]string{
+			"user_id",
+			"amount",
+		}).
+		Union(Select("account").
+			Select([]string{
+				"user_id",
+				"amount",
+			}).
+			Order([]string{
+				"amount DESC",
+			}).
+			Limit(0, 5),
+		).
+		Union(Select("account").
+			Select([]string{
+				"user_id",
+				"amount",
+			}).
+			Where(Where().
+				Eq("key", "a"),
+			),
+		).
+		Where(Where().
+			Gt("amount", 10),
+		).
+		Order([]string{
+			"amount",
+		})
+	
+	want :=
+`SELECT user_id, amount
+FROM (
+(
+SELECT user_id, amount
+FROM .account
+ORDER BY amount DESC
+LIMIT 0,5
+)
+UNION ALL
+SELECT user_id, amount
+FROM .account
+WHERE key=a
+) t
+WHERE amount>10
+ORDER BY amount`
+	got := SQL_debug(query)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+}
+
+func run_set_nested(tb testing.TB){
+	query := Union().
+		Union(Select("user").
+			Select([]string{
+				"id",
+			}),
+		).
+		Intersect(Union_all().
+			Union(Select("account").
+				Select([]string{
+					"user_id id",
+				}),
+			).
+			Union(Union().
+				Select([]string{
+					"id",
+				}).
+				Union(Select("user").
+					Select([]string{
+						"id",
+					}).
+					Where(Where().
+						Eq("client_id", 1),
+					),
+				).
+				Except(Select("user").
+					Select([]string{
+						"id",
+					}).
+					Where(Where().
+						Eq("name", "test"),
+					),
+				).
+				Where(Where().
+					Gt("id", 100),
+				),
+			),
+		).
+		Limit(0, 10)
+	
+	want :=
+`SELECT id
+FROM .user
+INTERSECT
+(
+SELECT user_id id
+FROM .account
+UNION ALL
+(
+SELECT id
+FROM (
+SELECT id
+FROM .user
+WHERE client_id=1
+EXCEPT
+SELECT id
+FROM .user
+WHERE name=test
+) t
+WHERE id>100
+)
+)
+LIMIT 0,10`
+	got := SQL_debug(query)
+	if got != want {
+		tb.Fatalf("SQL want:\n%s\nSQL got:\n%s", want, got)
+	}
+}
+
+func run_set_validate(tb testing.TB){
+	query := Union().
+		Select([]string{
+			"id",
+		}).
+		Union(Union().
+			Union(Select("user").
+				Select([]string{
+					"id",
+				}),
+			).
+			Except(Select("account").
+				Select([]string{
+					"user_id id",
+				}).
+				Where(Where().
+					Eq("kee", "a"),
+				),
+			),
+		).
+		Order([]string{
+			"name",
+		})
+	
+	want := "Unknown column: account.kee\nUnknown column: name"
+	err := query.Validate(validate_schema)
+	if err == nil || err.Error() != want {
+		tb.Fatalf("Validate want:\n%s\nValidate got:\n%v", want, err)
+	}
+	
+	if _, _, err := Union().Compile(); err == nil || err.Error() != "Must have at least two queries to union" {
+		tb.Fatalf("Error want:\n%s\nError got:\n%v", "Must have at least two queries to union", err)
+	}
 }
\ No newline at end of file
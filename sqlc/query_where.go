@@ -60,7 +60,7 @@ func (q *query_where) compile_where(ctx *compiler, inner_condition func(ctx *com
 			duplicates = make(map[string]Operator, 2)
 		}
 		
-		if err := q.walk_where_clause(ctx, q.where_clause, &duplicates, &first); err != nil {
+		if err := q.walk_where_clause(ctx, q.where_clause, &duplicates, &first, nil); err != nil {
 			return err
 		}
 	}
@@ -69,19 +69,55 @@ func (q *query_where) compile_where(ctx *compiler, inner_condition func(ctx *com
 	return nil
 }
 
-func (q *query_where) walk_where_clause(ctx *compiler, clause *Where_clause, duplicates *map[string]Operator, first *bool) error {
+//	Conditions are written with "write_field" if given (i.e. HAVING)
+func (q *query_where) walk_where_clause(ctx *compiler, clause *Where_clause, duplicates *map[string]Operator, first *bool, write_field func(ctx *compiler, field string)) error {
+	return q.write_node(ctx, clause, false, duplicates, first, write_field)
+}
+
+//	Write the clause as a term in a scope joined with AND (or: OR)
+func (q *query_where) write_node(ctx *compiler, node *Where_clause, or bool, duplicates *map[string]Operator, first *bool, write_field func(ctx *compiler, field string)) error {
+	if node.empty() {
+		return nil
+	}
+	
+	//	Same operator or a single term is merged into the scope
+	if !node.not && (node.or == or || node.terms() == 1) {
+		return q.write_terms(ctx, node, or, duplicates, first, write_field)
+	}
+	
+	write_separator(ctx, or, first)
+	if node.not {
+		ctx.sb.WriteString("NOT ")
+	}
+	return q.write_group(ctx, node, node.or, write_field)
+}
+
+//	Terms in parentheses have their own scope of operators on the same field
+func (q *query_where) write_group(ctx *compiler, clause *Where_clause, or bool, write_field func(ctx *compiler, field string)) error {
+	var duplicates map[string]Operator
+	first := true
+	
+	ctx.sb.WriteByte('(')
+	if err := q.write_terms(ctx, clause, or, &duplicates, &first, write_field); err != nil {
+		return err
+	}
+	ctx.sb.WriteByte(')')
+	return nil
+}
+
+func (q *query_where) write_terms(ctx *compiler, clause *Where_clause, or bool, duplicates *map[string]Operator, first *bool, write_field func(ctx *compiler, field string)) error {
 	//	Apply wrapped conditions
-	if clause.wrapped != nil {
-		if err := q.walk_where_clause(ctx, clause.wrapped, duplicates, first); err != nil {
-			return err
-		}
+	if err := q.write_node(ctx, clause.wrapped, or, duplicates, first, write_field); err != nil {
+		return err
 	}
 	
 	//	Apply conditions
 	for i := range clause.conditions {
 		condition := &clause.conditions[i]	//	Avoid copying data
 		
-		if *duplicates != nil {
+		if condition.field == "" || or {
+			//	No field to check (EXISTS, MATCH, raw and JSON) or conditions are not ANDed
+		} else if *duplicates != nil {
 			if operator, ok := (*duplicates)[condition.field]; ok {
 				if err := check_operator_compatibility(operator, condition.operator, condition.field); err != nil {
 					return err
@@ -94,63 +130,96 @@ func (q *query_where) walk_where_clause(ctx *compiler, clause *Where_clause, dup
 			(*duplicates)[condition.field] = condition.operator
 		}
 		
-		if *first {
-			*first = false
-		} else {
-			ctx.sb.WriteString(" AND ")
-		}
-		
-		if err := q.write_condition_data(ctx, condition); err != nil {
+		write_separator(ctx, or, first)
+		if err := q.write_condition_data(ctx, condition, write_field); err != nil {
 			return err
 		}
 	}
 	
 	//	Apply "or groups"
-	if clause.or_groups != nil {
-		for _, group := range clause.or_groups {
-			if *first {
-				*first = false
-			} else {
-				ctx.sb.WriteString(" AND ")
+	for _, group := range clause.or_groups {
+		if group.empty() {
+			continue
+		}
+		//	Not() node as or group
+		if group.not {
+			if err := q.write_node(ctx, group, or, duplicates, first, write_field); err != nil {
+				return err
 			}
-			
-			ctx.sb.WriteByte('(')
-			for i := range group.conditions {
-				condition := &group.conditions[i]	//	Avoid copying data
-				
-				if i > 0 {
-					ctx.sb.WriteString(" OR ")
-				}
-				
-				if err := q.write_condition_data(ctx, condition); err != nil {
-					return err
-				}
+			continue
+		}
+		if or {
+			if err := q.write_terms(ctx, group, true, duplicates, first, write_field); err != nil {
+				return err
 			}
-			ctx.sb.WriteByte(')')
+			continue
+		}
+		write_separator(ctx, or, first)
+		if err := q.write_group(ctx, group, true, write_field); err != nil {
+			return err
 		}
 	}
 	
+	//	Apply expression nodes
+	for _, node := range clause.nodes {
+		if err := q.write_node(ctx, node, or, duplicates, first, write_field); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func (q *query_where) write_condition_data(ctx *compiler, condition *where_condition) error {
-	ctx.write_field(q.t, condition.field)
-	sub_data, err := write_operator_condition(&ctx.sb, condition.operator, condition.value)
-	if err != nil {
-		return err
+func write_separator(ctx *compiler, or bool, first *bool){
+	switch {
+	case *first:
+		*first = false
+	case or:
+		ctx.sb.WriteString(" OR ")
+	default:
+		ctx.sb.WriteString(" AND ")
 	}
-	
-	if condition.operator == Op_null || condition.operator == Op_not_null {
+}
+
+func (q *query_where) write_condition_data(ctx *compiler, condition *where_condition, write_field func(ctx *compiler, field string)) error {
+	switch v := condition.value.(type) {
+	case *correlated_subquery:
+		return q.write_exists(ctx, condition.operator, v)
+	case *match_against:
+		q.write_match(ctx, v)
 		return nil
+	case *where_raw:
+		return write_raw_condition(ctx, v)
+	case *json_condition:
+		return q.write_json_condition(ctx, v)
 	}
-	
-	//	Apply data
-	if sub_data != nil {
-		ctx.append_data(sub_data)
+	if write_field != nil {
+		write_field(ctx, condition.field)
+		//	Field values are written the same way (select aliases and functions in HAVING)
+		if col, ok := condition.value.(Col); ok {
+			if err := write_col_operator(ctx, condition.operator, col); err != nil {
+				return err
+			}
+			write_field(ctx, string(col))
+			return nil
+		}
 	} else {
-		ctx.append_data(condition.value)
+		ctx.write_field(q.t, condition.field)
+	}
+	return write_operator_condition(ctx, q.t, condition.operator, condition.value)
+}
+
+//	Apply both inner conditions to the where clause
+func merge_conditions(a, b func(ctx *compiler, first *bool)) func(ctx *compiler, first *bool) {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	return func(ctx *compiler, first *bool){
+		a(ctx, first)
+		b(ctx, first)
 	}
-	return nil
 }
 
 func (q *query_where) get_alloc() (int, int, int){
@@ -161,14 +230,15 @@ func (q *query_where) get_alloc() (int, int, int){
 }
 
 func check_operator_compatibility(current_operator, new_operator Operator, field string) error {
-	if current_operator == new_operator {
+	//	Multiple patterns on same field
+	if current_operator == new_operator && !pattern_operator(current_operator) {
 		return where_operator_error(field, current_operator, new_operator)
 	}
 	
 	switch current_operator {
 	//	Operator not compatable with "oposite" operators
 	case Op_null:
-		if new_operator == Op_not_null {
+		if new_operator == Op_not_null || pattern_operator(new_operator) {
 			return where_operator_error(field, current_operator, new_operator)
 		}
 	case Op_not_null:
@@ -189,10 +259,24 @@ func check_operator_compatibility(current_operator, new_operator Operator, field
 		if new_operator != Op_gt && new_operator != Op_gteq {
 			return where_operator_error(field, current_operator, new_operator)
 		}
+	
+	//	Operator only compatable with pattern operators and "not null"
+	case Op_like, Op_not_like, Op_regexp, Op_not_regexp:
+		if !pattern_operator(new_operator) && new_operator != Op_not_null {
+			return where_operator_error(field, current_operator, new_operator)
+		}
 	}
 	return nil
 }
 
+func pattern_operator(operator Operator) bool {
+	switch operator {
+	case Op_like, Op_not_like, Op_regexp, Op_not_regexp:
+		return true
+	}
+	return false
+}
+
 func where_operator_error(field string, current_operator, new_operator Operator) error {
 	return fmt.Errorf("Where clause operator incompatable on same field (%s): %s %s", field, sql_ops[current_operator], sql_ops[new_operator])
 }
\ No newline at end of file
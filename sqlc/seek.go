@@ -0,0 +1,355 @@
+package sqlc
+
+import (
+	"fmt"
+	"time"
+	"bytes"
+	"slices"
+	"reflect"
+	"strconv"
+	"strings"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
+	"encoding/base64"
+	"database/sql/driver"
+)
+
+var cursor_key []byte
+
+type (
+	select_seek struct {
+		values		[]any
+		before		bool	//	Previous page (order is reversed)
+		cursor		*cursor_payload
+		err			error
+	}
+	
+	seek_order struct {
+		field		string
+		desc		bool
+	}
+	
+	cursor_payload struct {
+		Table		string			`json:"t"`
+		Order		[]string		`json:"o"`
+		Before		bool			`json:"b,omitempty"`
+		Values		[]cursor_value	`json:"v"`
+	}
+	
+	cursor_value struct {
+		Type		string			`json:"t"`
+		Value		string			`json:"v,omitempty"`
+	}
+)
+
+//	Key to sign cursor tokens (HMAC-SHA256)
+func Cursor_key(key []byte){
+	if len(key) == 0 {
+		panic("Cursor key is empty")
+	}
+	cursor_key = key
+}
+
+//	Keyset pagination: Rows after the row with the values of the order fields
+func (q *Select_query) Seek(values ...any) *Select_query {
+	q.seek = &select_seek{
+		values:	values,
+	}
+	return q
+}
+
+//	Keyset pagination: Rows before the row with the values of the order fields (rows are returned in reverse order)
+func (q *Select_query) Seek_before(values ...any) *Select_query {
+	q.seek = &select_seek{
+		values:	values,
+		before:	true,
+	}
+	return q
+}
+
+//	Keyset pagination from a cursor token (Next_cursor or Prev_cursor)
+func (q *Select_query) Cursor(token string) *Select_query {
+	payload, err := decode_cursor(token)
+	if err != nil {
+		q.seek = &select_seek{
+			err:	err,
+		}
+		return q
+	}
+	
+	values := make([]any, len(payload.Values))
+	for i, v := range payload.Values {
+		if values[i], err = v.decode(); err != nil {
+			q.seek = &select_seek{
+				err:	err,
+			}
+			return q
+		}
+	}
+	q.seek = &select_seek{
+		values:	values,
+		before:	payload.Before,
+		cursor:	&payload,
+	}
+	return q
+}
+
+//	Rows are returned in reverse order (previous page)
+func (q *Select_query) Reversed() bool {
+	return q.seek != nil && q.seek.before
+}
+
+//	Cursor token for the page after the last row (values of the order fields)
+func (q *Select_query) Next_cursor(last ...any) (string, error){
+	return q.encode_cursor(last, false)
+}
+
+//	Cursor token for the page before the first row (values of the order fields)
+func (q *Select_query) Prev_cursor(first ...any) (string, error){
+	return q.encode_cursor(first, true)
+}
+
+func (q *Select_query) encode_cursor(values []any, before bool) (string, error){
+	if len(values) != len(q.order) {
+		return "", fmt.Errorf("Cursor must have a value for each order field: %d values %d order fields", len(values), len(q.order))
+	}
+	payload := cursor_payload{
+		Table:	q.table,
+		Order:	q.order,
+		Before:	before,
+		Values:	make([]cursor_value, len(values)),
+	}
+	for i, v := range values {
+		var err error
+		if payload.Values[i], err = encode_cursor_value(v); err != nil {
+			return "", err
+		}
+	}
+	return encode_cursor(payload)
+}
+
+//	Keyset condition: "(a, b) > (?, ?)" or "(a>? OR a=? AND b<?)" when order directions differ
+func (q *Select_query) seek_condition() func(ctx *compiler, first *bool) {
+	if q.seek == nil || q.seek.err != nil {
+		return nil
+	}
+	
+	return func(ctx *compiler, first *bool){
+		if *first {
+			*first = false
+		} else {
+			ctx.sb.WriteString(" AND ")
+		}
+		
+		order := q.seek_order()
+		
+		//	Same direction for all fields
+		same := true
+		for _, o := range order[1:] {
+			if o.desc != order[0].desc {
+				same = false
+				break
+			}
+		}
+		
+		if same {
+			if len(order) > 1 {
+				ctx.sb.WriteByte('(')
+			}
+			for i, o := range order {
+				if i > 0 {
+					ctx.sb.WriteString(", ")
+				}
+				ctx.write_field(q.t, o.field)
+			}
+			if len(order) > 1 {
+				ctx.sb.WriteByte(')')
+			}
+			ctx.sb.WriteString(q.seek_operator(order[0]))
+			if len(order) > 1 {
+				ctx.sb.WriteByte('(')
+				field_placeholder_list(len(order), &ctx.sb)
+				ctx.sb.WriteByte(')')
+			} else {
+				ctx.sb.WriteByte('?')
+			}
+			for _, v := range q.seek.values {
+				ctx.append_data(v)
+			}
+			return
+		}
+		
+		ctx.sb.WriteByte('(')
+		for i, o := range order {
+			if i > 0 {
+				ctx.sb.WriteString(" OR ")
+			}
+			for j := range i {
+				ctx.write_field(q.t, order[j].field)
+				ctx.sb.WriteString("=? AND ")
+				ctx.append_data(q.seek.values[j])
+			}
+			ctx.write_field(q.t, o.field)
+			ctx.sb.WriteString(q.seek_operator(o))
+			ctx.sb.WriteByte('?')
+			ctx.append_data(q.seek.values[i])
+		}
+		ctx.sb.WriteByte(')')
+	}
+}
+
+func (q *Select_query) seek_operator(o seek_order) string {
+	if o.desc != q.seek.before {
+		return "<"
+	}
+	return ">"
+}
+
+func (q *Select_query) seek_order() []seek_order {
+	order := make([]seek_order, len(q.order))
+	for i, v := range q.order {
+		order[i].field = v
+		if pos := strings.IndexByte(v, ' '); pos != -1 {
+			order[i].field	= v[:pos]
+			order[i].desc	= strings.EqualFold(strings.TrimSpace(v[pos+1:]), "DESC")
+		}
+	}
+	return order
+}
+
+func (q *Select_query) seek_error() error {
+	if q.seek == nil {
+		return nil
+	}
+	if q.seek.err != nil {
+		return q.seek.err
+	}
+	if len(q.order) == 0 {
+		return fmt.Errorf("Seek requires order fields")
+	}
+	//	Cursor must be created from the same query
+	if c := q.seek.cursor; c != nil && (c.Table != q.table || !slices.Equal(c.Order, q.order)) {
+		return fmt.Errorf("Cursor does not match query")
+	}
+	if len(q.seek.values) != len(q.order) {
+		return fmt.Errorf("Seek must have a value for each order field: %d values %d order fields", len(q.seek.values), len(q.order))
+	}
+	return nil
+}
+
+func encode_cursor(payload cursor_payload) (string, error){
+	if cursor_key == nil {
+		return "", fmt.Errorf("Cursor key is not set")
+	}
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("Unable to encode cursor: %w", err)
+	}
+	mac := hmac.New(sha256.New, cursor_key)
+	mac.Write(b)
+	return base64.RawURLEncoding.EncodeToString(b)+"."+base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+func decode_cursor(token string) (cursor_payload, error){
+	var payload cursor_payload
+	if cursor_key == nil {
+		return payload, fmt.Errorf("Cursor key is not set")
+	}
+	
+	data, sig, found := strings.Cut(token, ".")
+	if !found {
+		return payload, fmt.Errorf("Invalid cursor")
+	}
+	b, err := base64.RawURLEncoding.DecodeString(data)
+	if err != nil {
+		return payload, fmt.Errorf("Invalid cursor")
+	}
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return payload, fmt.Errorf("Invalid cursor")
+	}
+	mac := hmac.New(sha256.New, cursor_key)
+	mac.Write(b)
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return payload, fmt.Errorf("Invalid cursor signature")
+	}
+	
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&payload); err != nil {
+		return payload, fmt.Errorf("Invalid cursor")
+	}
+	return payload, nil
+}
+
+//	Values keep their type through the cursor token
+func encode_cursor_value(v any) (cursor_value, error){
+	switch t := v.(type) {
+	case nil:
+		return cursor_value{Type: "n"}, nil
+	case string:
+		return cursor_value{"s", t}, nil
+	case []byte:
+		return cursor_value{"x", base64.RawStdEncoding.EncodeToString(t)}, nil
+	case bool:
+		return cursor_value{"b", strconv.FormatBool(t)}, nil
+	case time.Time:
+		return cursor_value{"d", t.Format(time.RFC3339Nano)}, nil
+	case driver.Valuer:
+		value, err := t.Value()
+		if err != nil {
+			return cursor_value{}, err
+		}
+		return encode_cursor_value(value)
+	}
+	
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cursor_value{"i", strconv.FormatInt(rv.Int(), 10)}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return cursor_value{"u", strconv.FormatUint(rv.Uint(), 10)}, nil
+	case reflect.Float32, reflect.Float64:
+		return cursor_value{"f", strconv.FormatFloat(rv.Float(), 'g', -1, 64)}, nil
+	case reflect.String:
+		return cursor_value{"s", rv.String()}, nil
+	case reflect.Pointer:
+		if rv.IsNil() {
+			return cursor_value{Type: "n"}, nil
+		}
+		return encode_cursor_value(rv.Elem().Interface())
+	}
+	return cursor_value{}, fmt.Errorf("Unsupported cursor value type: %T", v)
+}
+
+func (c cursor_value) decode() (any, error){
+	var (
+		v	any
+		err	error
+	)
+	switch c.Type {
+	case "n":
+		return nil, nil
+	case "s":
+		return c.Value, nil
+	case "x":
+		v, err = base64.RawStdEncoding.DecodeString(c.Value)
+	case "b":
+		v, err = strconv.ParseBool(c.Value)
+	case "d":
+		v, err = time.Parse(time.RFC3339Nano, c.Value)
+	case "i":
+		v, err = strconv.ParseInt(c.Value, 10, 64)
+	case "u":
+		v, err = strconv.ParseUint(c.Value, 10, 64)
+	case "f":
+		v, err = strconv.ParseFloat(c.Value, 64)
+	default:
+		return nil, fmt.Errorf("Invalid cursor")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Invalid cursor")
+	}
+	return v, nil
+}
\ No newline at end of file
@@ -1,7 +1,7 @@
 package sqlc
 
 import (
-	"fmt"
+	"slices"
 	"strings"
 	"strconv"
 )
@@ -18,27 +18,34 @@ type (
 		select_distinct	bool
 		select_jsons	[]*select_json
 		group			[]string
+		having			*Where_clause
+		windows			[]select_window
 		order 			[]string
+		order_aliases	[]string	//	Select aliases in ORDER BY (relevance)
 		limit 			select_limit
-		lock_for_update		bool
+		seek			*select_seek
+		lock			select_lock
+		straight_join	bool
 	}
 	
 	select_field struct {
 		field 			string
 		function		string
 		alias 			string
+		window			*Window_func
+		subquery		*correlated_subquery
+		match			*match_against
+		extract			*json_extract
 	}
 	
 	select_limit struct {
 		offset 			uint32
-		limit 			uint8
+		limit 			uint32
 	}
 	
 	select_json struct {
 		select_field	string
-		query			*Select_query
-		inner_field		string
-		outer_field		string
+		Json_agg
 	}
 )
 
@@ -61,18 +68,21 @@ func Select(table string) *Select_query {
 	}
 }
 
-func (q *Select_query) Lock_for_update() *Select_query {
-	q.lock_for_update = true
-	return q
-}
-
 func (q *Select_query) Optimize_joins() *Select_query {
 	q.optimize_joins = true
 	return q
 }
 
+//	Replaces the fields of earlier calls: Window, subquery, match and JSON path fields are kept after the fields
 func (q *Select_query) Select(list []string) *Select_query {
-	q.select_fields = make([]select_field, len(list))
+	fields := make([]select_field, len(list), len(list) + len(q.select_fields))
+	for _, f := range q.select_fields {
+		if f.window != nil || f.subquery != nil || f.match != nil || f.extract != nil {
+			fields = append(fields, f)
+		}
+	}
+	q.select_fields = fields
+	
 	for i, v := range list {
 		f := &q.select_fields[i]	//	Avoid copying data
 		
@@ -96,10 +106,36 @@ func (q *Select_query) Select_distinct(list []string) *Select_query {
 	return q
 }
 
+//	Scalar subquery as a select field: "(SELECT ...) alias"
+func (q *Select_query) Select_subquery(alias string, query *Select_query) *Select_query {
+	q.select_fields = append(q.select_fields, select_field{
+		alias:		alias,
+		subquery:	&correlated_subquery{
+			query:	query,
+		},
+	})
+	return q
+}
+
+//	Correlated scalar subquery: Inner field (subquery) equals outer field
+func (q *Select_query) Select_subquery_condition(alias string, query *Select_query, inner_field, outer_field string) *Select_query {
+	q.select_fields = append(q.select_fields, select_field{
+		alias:		alias,
+		subquery:	&correlated_subquery{
+			query:			query,
+			inner_field:	inner_field,
+			outer_field:	outer_field,
+		},
+	})
+	return q
+}
+
 func (q *Select_query) Select_json(field string, query *Select_query) *Select_query {
 	q.select_jsons = append(q.select_jsons, &select_json{
 		select_field:	field,
-		query:			query,
+		Json_agg:		Json_agg{
+			query:	query,
+		},
 	})
 	return q
 }
@@ -107,13 +143,21 @@ func (q *Select_query) Select_json(field string, query *Select_query) *Select_qu
 func (q *Select_query) Select_json_condition(field string, query *Select_query, inner_field, outer_field string) *Select_query {
 	q.select_jsons = append(q.select_jsons, &select_json{
 		select_field:	field,
-		query:			query,
-		inner_field:	inner_field,
-		outer_field:	outer_field,
+		Json_agg:		Json_agg{
+			query:			query,
+			inner_field:	inner_field,
+			outer_field:	outer_field,
+		},
 	})
 	return q
 }
 
+//	Common table expressions referenced as tables in FROM and joins
+func (q *Select_query) With(ctes ...*Cte) *Select_query {
+	q.with(ctes)
+	return q
+}
+
 func (q *Select_query) Inner_join(table, t, field, field_foreign string) *Select_query {
 	q.inner_join(table, t, field, field_foreign)
 	return q
@@ -149,6 +193,18 @@ func (q *Select_query) Left_join_multi(table, t string, fields Join_conditions)
 	return q
 }
 
+//	Join on a subquery (derived table) with alias t
+func (q *Select_query) Inner_join_subquery(query *Select_query, t string, fields Join_conditions) *Select_query {
+	q.join_subquery(join_inner, query, t, fields)
+	return q
+}
+
+//	Join on a subquery (derived table) with alias t
+func (q *Select_query) Left_join_subquery(query *Select_query, t string, fields Join_conditions) *Select_query {
+	q.join_subquery(join_left, query, t, fields)
+	return q
+}
+
 func (q *Select_query) Where(clause *Where_clause) *Select_query {
 	q.where_clause = clause
 	return q
@@ -159,12 +215,18 @@ func (q *Select_query) Group(fields []string) *Select_query {
 	return q
 }
 
+//	Conditions on aggregate functions ("sum|amount") and select aliases
+func (q *Select_query) Having(clause *Where_clause) *Select_query {
+	q.having = clause
+	return q
+}
+
 func (q *Select_query) Order(fields []string) *Select_query {
 	q.order = fields
 	return q
 }
 
-func (q *Select_query) Limit(offset uint32, limit uint8) *Select_query {
+func (q *Select_query) Limit(offset, limit uint32) *Select_query {
 	q.limit = select_limit{offset, limit}
 	return q
 }
@@ -176,9 +238,24 @@ func (q *Select_query) Compile() (string, []any, error){
 		compiler_pool.Put(ctx)
 	}()
 	
+	if err := q.compile(ctx, nil); err != nil {
+		return "", nil, err
+	}
+	return ctx.sb.String(), ctx.data, nil
+}
+
+//	Inner condition is applied to the where clause (correlated subqueries)
+func (q *Select_query) compile(ctx *compiler, inner_condition func(ctx *compiler, first *bool)) error {
+	if err := q.seek_error(); err != nil {
+		return err
+	}
+	if err := q.window_error(); err != nil {
+		return err
+	}
+	
 	var aliases alias_collect
 	
-	if q.joined || q.select_jsons != nil {
+	if q.joined || q.select_jsons != nil || q.select_correlated() || q.where_clause.correlated() {
 		ctx.use_alias = true
 		
 		if q.optimize_joins {
@@ -188,14 +265,17 @@ func (q *Select_query) Compile() (string, []any, error){
 				alias_collect_pool.Put(aliases)
 			}()
 			if err := q.collect_aliases(aliases); err != nil {
-				return "", nil, err
+				return err
 			}
 		}
 	}
 	
+	if err := q.compile_with(ctx); err != nil {
+		return err
+	}
 	t := q.base_table_short()
 	if err := q.compile_tables(ctx, t); err != nil {
-		return "", nil, err
+		return err
 	}
 	ctx.root_t = q.t
 	
@@ -218,32 +298,46 @@ func (q *Select_query) Compile() (string, []any, error){
 	
 	var err error
 	if err = q.compile_select(ctx); err != nil {
-		return "", nil, err
+		return err
 	}
 	q.compile_from(ctx)
 	if err = q.compile_joins(ctx, aliases); err != nil {
-		return "", nil, err
+		return err
 	}
 	//audit.Audit()
-	if err = q.compile_where(ctx, nil); err != nil {
-		return "", nil, err
+	if err = q.compile_where(ctx, merge_conditions(q.seek_condition(), inner_condition)); err != nil {
+		return err
 	}
 	q.compile_group(ctx)
+	if err = q.compile_having(ctx); err != nil {
+		return err
+	}
+	q.compile_windows(ctx)
 	q.compile_order(ctx)
 	q.compile_limit(ctx)
-	if q.lock_for_update {
-		ctx.sb.WriteString("FOR UPDATE\n")
-	}
-	
-	return ctx.sb.String(), ctx.data, nil
+	return q.compile_lock(ctx)
 }
 
 func (q *Select_query) collect_aliases(list alias_collect) error {
 	//	Check SELECT clause
 	for _, f := range q.select_fields {
-		if f.function == SELECT_RAW {
+		switch {
+		case f.window != nil:
+			list.apply(f.window.field)
+			if f.window.spec != nil {
+				f.window.spec.collect_aliases(list)
+			}
+		case f.subquery != nil:
+			list.apply(f.subquery.outer_field)
+		case f.match != nil:
+			for _, field := range f.match.fields {
+				list.apply(field)
+			}
+		case f.extract != nil:
+			list.apply(f.extract.field)
+		case f.function == SELECT_RAW:
 			list.apply_raw(f.field)
-		} else {
+		default:
 			list.apply(f.field)
 		}
 	}
@@ -262,19 +356,39 @@ func (q *Select_query) collect_aliases(list alias_collect) error {
 		list.apply(f)
 	}
 	
+	//	Check HAVING clause
+	if err := q.having.collect_aliases(list); err != nil {
+		return err
+	}
+	
+	//	Check WINDOW clause
+	for _, w := range q.windows {
+		w.spec.collect_aliases(list)
+	}
+	
 	//	Check ORDER clause
 	for _, f := range q.order {
 		list.apply(f)
 	}
 	
+	//	Check lock tables
+	for _, table := range q.lock.of {
+		if table != q.table {
+			list[table] = struct{}{}
+		}
+	}
+	
 	return q.resolve_alias_join_dependencies(list)
 }
 
 func (q *Select_query) compile_select(ctx *compiler) error {
+	ctx.sb.WriteString("SELECT ")
+	q.write_optimizer_hints(ctx)
 	if q.select_distinct {
-		ctx.sb.WriteString("SELECT DISTINCT ")
-	} else {
-		ctx.sb.WriteString("SELECT ")
+		ctx.sb.WriteString("DISTINCT ")
+	}
+	if q.straight_join {
+		ctx.sb.WriteString("STRAIGHT_JOIN ")
 	}
 	
 	for i := range q.select_fields {
@@ -283,24 +397,8 @@ func (q *Select_query) compile_select(ctx *compiler) error {
 			ctx.sb.WriteString(", ")
 		}
 		
-		switch s.function {
-		case "":
-			ctx.write_field(q.t, s.field)
-		case SELECT_RAW:
-			if strings.Contains(s.field, ROOT_ALIAS) {
-				ctx.sb.WriteString(strings.ReplaceAll(s.field, ROOT_ALIAS+".", q.t+"."))
-			} else {
-				ctx.sb.WriteString(s.field)
-			}
-		case SELECT_SUM_ZERO:
-			ctx.sb.WriteString("IFNULL(SUM(")
-			ctx.write_field(q.t, s.field)
-			ctx.sb.WriteString("), 0)")
-		default:
-			ctx.sb.WriteString(strings.ToUpper(s.function))
-			ctx.sb.WriteByte('(')
-			ctx.write_field(q.t, s.field)
-			ctx.sb.WriteByte(')')
+		if err := q.write_select_field(ctx, s); err != nil {
+			return err
 		}
 		
 		if s.alias != "" {
@@ -317,6 +415,44 @@ func (q *Select_query) compile_select(ctx *compiler) error {
 	return nil
 }
 
+func (q *Select_query) write_select_field(ctx *compiler, s *select_field) error {
+	switch {
+	case s.window != nil:
+		q.write_window_func(ctx, s.window)
+	case s.subquery != nil:
+		return q.write_select_subquery(ctx, s.subquery)
+	case s.match != nil:
+		q.write_match(ctx, s.match)
+	case s.extract != nil:
+		return q.write_json_extract(ctx, s.extract)
+	default:
+		q.write_select_function(ctx, s.function, s.field)
+	}
+	return nil
+}
+
+func (q *Select_query) write_select_function(ctx *compiler, function, field string){
+	switch function {
+	case "":
+		ctx.write_field(q.t, field)
+	case SELECT_RAW:
+		if strings.Contains(field, ROOT_ALIAS) {
+			ctx.sb.WriteString(strings.ReplaceAll(field, ROOT_ALIAS+".", q.t+"."))
+		} else {
+			ctx.sb.WriteString(field)
+		}
+	case SELECT_SUM_ZERO:
+		ctx.sb.WriteString("IFNULL(SUM(")
+		ctx.write_field(q.t, field)
+		ctx.sb.WriteString("), 0)")
+	default:
+		ctx.sb.WriteString(strings.ToUpper(function))
+		ctx.sb.WriteByte('(')
+		ctx.write_field(q.t, field)
+		ctx.sb.WriteByte(')')
+	}
+}
+
 func (q *Select_query) compile_select_joins(ctx *compiler) error {
 	var err error
 	for _, sj := range q.select_jsons {
@@ -339,77 +475,12 @@ func (q *Select_query) compile_select_null(ctx *compiler, sj *select_json) {
 }
 
 func (q *Select_query) compile_select_join(ctx *compiler, sj *select_json) error {
-	if len(sj.query.select_fields) < 2 {
-		return fmt.Errorf("Minimum 2 fields in select json")
-	}
-	
-	var sub_aliases alias_collect
-	
-	if sj.query.joined && sj.query.optimize_joins {
-		sub_aliases = alias_collect_pool.Get().(alias_collect)
-		defer func() {
-			sub_aliases.reset()
-			alias_collect_pool.Put(sub_aliases)
-		}()
-		if err := sj.query.collect_aliases(sub_aliases); err != nil {
-			return err
-		}
-	}
-	
-	t := sj.query.base_table_short()
-	if err := sj.query.compile_tables(ctx, t); err != nil {
-		return err
-	}
-	
-	ctx.sb.WriteString(",\n(\nSELECT JSON_ARRAYAGG(JSON_OBJECT(")
-	for i := range sj.query.select_fields {
-		field := &sj.query.select_fields[i]	//	Avoid copying data
-		if i > 0 {
-			ctx.sb.WriteString(", ")
-		}
-		ctx.sb.WriteByte('\'')
-		if field.alias == "" {
-			if pos := strings.IndexByte(field.field, '.'); pos != -1 {
-				ctx.sb.WriteString(field.field[pos+1:])
-			} else {
-				ctx.sb.WriteString(field.field)
-			}
-		} else {
-			ctx.sb.WriteString(field.alias)
-		}
-		ctx.sb.WriteString("', ")
-		ctx.write_field(sj.query.t, field.field)
-	}
-	ctx.sb.WriteString("))\n")
-	
-	sj.query.compile_from(ctx)
-	sj.query.compile_joins(ctx, sub_aliases)
-	
-	if err := sj.query.compile_where(ctx, func(ctx *compiler, first *bool){
-		if sj.inner_field == "" {
-			return
-		}
-		
-		if *first {
-			*first = false
-		} else {
-			ctx.sb.WriteString(" AND ")
-		}
-		
-		ctx.write_field(sj.query.t, sj.inner_field)
-		ctx.sb.WriteByte('=')
-		ctx.write_field(q.t, sj.outer_field)
-	}); err != nil {
+	ctx.sb.WriteString(",\n")
+	if err := q.write_json_subquery(ctx, sj); err != nil {
 		return err
 	}
-	
-	sj.query.compile_group(ctx)
-	sj.query.compile_order(ctx)
-	sj.query.compile_limit(ctx)
-	
-	ctx.sb.WriteString(") ")
+	ctx.sb.WriteByte(' ')
 	ctx.sb.WriteString(sj.select_field)
-	
 	return nil
 }
 
@@ -432,6 +503,65 @@ func (q *Select_query) compile_group(ctx *compiler){
 	ctx.sb.WriteByte('\n')
 }
 
+func (q *Select_query) compile_having(ctx *compiler) error {
+	if q.having == nil {
+		return nil
+	}
+	num, alloc, alloc_data := q.having.get_alloc()
+	if num == 0 {
+		return nil
+	}
+	
+	//	Pre-allocation
+	alloc += 8 + num * 5	//	"HAVING \n" + " AND "
+	if ctx.use_alias {
+		alloc += num * 3
+	}
+	ctx.sb.Alloc(alloc)
+	ctx.alloc_data_capacity(alloc_data + len(ctx.data))
+	
+	ctx.sb.WriteString("HAVING ")
+	var (
+		first		= true
+		duplicates	map[string]Operator
+	)
+	if err := q.walk_where_clause(ctx, q.having, &duplicates, &first, q.write_having_field); err != nil {
+		return err
+	}
+	ctx.sb.WriteByte('\n')
+	return nil
+}
+
+//	Select aliases are written as is
+func (q *Select_query) write_having_field(ctx *compiler, field string){
+	if pos := strings.IndexByte(field, '|'); pos != -1 {
+		q.write_select_function(ctx, field[:pos], field[pos+1:])
+		return
+	}
+	if q.select_alias(field) {
+		ctx.sb.WriteString(field)
+		return
+	}
+	ctx.write_field(q.t, field)
+}
+
+func (q *Select_query) select_alias(field string) bool {
+	for i := range q.select_fields {
+		if q.select_fields[i].alias == field {
+			return true
+		}
+		if pos := strings.IndexByte(q.select_fields[i].field, ' '); pos != -1 && q.select_fields[i].field[pos+1:] == field {
+			return true
+		}
+	}
+	for _, sj := range q.select_jsons {
+		if sj.select_field == field {
+			return true
+		}
+	}
+	return false
+}
+
 func (q *Select_query) compile_order(ctx *compiler){
 	length := len(q.order)
 	if length == 0 {
@@ -442,22 +572,49 @@ func (q *Select_query) compile_order(ctx *compiler){
 	ctx.sb.Alloc(10 + q.alloc_field_list(length, ctx.use_alias))
 	
 	ctx.sb.WriteString("ORDER BY ")
+	if q.Reversed() {
+		q.compile_order_reversed(ctx)
+		return
+	}
 	for i, v := range q.order {
 		if i > 0 {
 			ctx.sb.WriteString(", ")
 		}
-		ctx.write_field(q.t, v)
+		q.write_order_field(ctx, v)
 	}
 	ctx.sb.WriteByte('\n')
 }
 
+//	Previous page with keyset pagination
+func (q *Select_query) compile_order_reversed(ctx *compiler){
+	for i, o := range q.seek_order() {
+		if i > 0 {
+			ctx.sb.WriteString(", ")
+		}
+		q.write_order_field(ctx, o.field)
+		if !o.desc {
+			ctx.sb.WriteString(" DESC")
+		}
+	}
+	ctx.sb.WriteByte('\n')
+}
+
+//	Relevance aliases are written as is
+func (q *Select_query) write_order_field(ctx *compiler, field string){
+	if slices.Contains(q.order_aliases, order_field(field)) {
+		ctx.sb.WriteString(field)
+		return
+	}
+	ctx.write_field(q.t, field)
+}
+
 func (q *Select_query) compile_limit(ctx *compiler){
 	if q.limit.limit == 0 {
 		return
 	}
 	
 	//	Pre-allocation
-	ctx.sb.Alloc(8 + 3 + 3)
+	ctx.sb.Alloc(8 + 10 + 10)
 	
 	var buf [20]byte
 	
@@ -0,0 +1,161 @@
+package sqlc
+
+import "fmt"
+
+type (
+	//	Inner field (subquery) equals outer field if given
+	correlated_subquery struct {
+		query			*Select_query
+		inner_field		string
+		outer_field		string
+	}
+	
+	//	Comparison with a subquery: "x>(SELECT ...)" or "x>ANY (SELECT ...)"
+	where_subquery struct {
+		query			*Select_query
+		quantifier		string
+	}
+)
+
+const (
+	quantifier_any	= "ANY "
+	quantifier_all	= "ALL "
+)
+
+var select_one = []select_field{{field: "1", function: SELECT_RAW}}
+
+//	Subqueries are compiled in their own scope (table aliases) with the CTE names of the outer query
+func compile_subquery(ctx *compiler, query Set_query) error {
+	sub := compiler_pool.Get().(*compiler)
+	defer func() {
+		sub.reset()
+		compiler_pool.Put(sub)
+	}()
+	
+	for name := range ctx.ctes {
+		sub.add_cte(name)
+	}
+	if err := query.compile(sub, nil); err != nil {
+		return err
+	}
+	ctx.sb.WriteString(sub.sb.String())
+	ctx.append_data(sub.data)
+	return nil
+}
+
+//	Correlated subqueries can not use the table aliases of the outer query
+func compile_correlated_subquery(ctx *compiler, query *Select_query, inner_field, outer_field, outer_t string) error {
+	sub := compiler_pool.Get().(*compiler)
+	defer func() {
+		sub.reset()
+		compiler_pool.Put(sub)
+	}()
+	
+	for name := range ctx.ctes {
+		sub.add_cte(name)
+	}
+	for alias, table := range ctx.tables {
+		sub.tables[alias] = table
+	}
+	
+	var inner_condition func(ctx *compiler, first *bool)
+	if inner_field != "" {
+		//	Inner and outer fields must be written with table aliases
+		sub.use_alias = true
+		inner_condition = func(sub *compiler, first *bool){
+			if *first {
+				*first = false
+			} else {
+				sub.sb.WriteString(" AND ")
+			}
+			sub.write_field(query.t, inner_field)
+			sub.sb.WriteByte('=')
+			sub.write_field(outer_t, outer_field)
+		}
+	}
+	if err := query.compile(sub, inner_condition); err != nil {
+		return err
+	}
+	ctx.sb.WriteString(sub.sb.String())
+	ctx.append_data(sub.data)
+	return nil
+}
+
+func (q *query_where) write_exists(ctx *compiler, operator Operator, e *correlated_subquery) error {
+	if operator == Op_not_exists {
+		ctx.sb.WriteString("NOT ")
+	}
+	ctx.sb.WriteString("EXISTS (\n")
+	
+	query := e.query
+	if len(query.select_fields) == 0 {
+		//	Copy to not change the subquery
+		c := *query
+		c.select_fields = select_one
+		query = &c
+	}
+	if err := compile_correlated_subquery(ctx, query, e.inner_field, e.outer_field, q.t); err != nil {
+		return err
+	}
+	ctx.sb.WriteByte(')')
+	return nil
+}
+
+func (q *Select_query) write_select_subquery(ctx *compiler, s *correlated_subquery) error {
+	ctx.sb.WriteString("(\n")
+	if err := compile_correlated_subquery(ctx, s.query, s.inner_field, s.outer_field, q.t); err != nil {
+		return err
+	}
+	ctx.sb.WriteByte(')')
+	return nil
+}
+
+func write_where_subquery(ctx *compiler, operator Operator, s *where_subquery) error {
+	if operator > Op_lteq {
+		return fmt.Errorf("Invalid subquery operator")
+	}
+	ctx.sb.WriteString(sql_ops[operator])
+	ctx.sb.WriteString(s.quantifier)
+	ctx.sb.WriteString("(\n")
+	if err := compile_subquery(ctx, s.query); err != nil {
+		return err
+	}
+	ctx.sb.WriteByte(')')
+	return nil
+}
+
+//	Select fields have correlated subqueries (outer table must have an alias)
+func (q *Select_query) select_correlated() bool {
+	for _, f := range q.select_fields {
+		if f.subquery != nil && f.subquery.outer_field != "" {
+			return true
+		}
+	}
+	return false
+}
+
+//	Where clause has correlated subqueries (outer table must have an alias)
+func (w *Where_clause) correlated() bool {
+	if w == nil {
+		return false
+	}
+	if w.wrapped.correlated() {
+		return true
+	}
+	for _, group := range w.or_groups {
+		if group.correlated() {
+			return true
+		}
+	}
+	for _, node := range w.nodes {
+		if node.correlated() {
+			return true
+		}
+	}
+	for _, condition := range w.conditions {
+		if e, ok := condition.value.(*correlated_subquery); ok && e.outer_field != "" {
+			return true
+		}
+	}
+	return false
+}
\ No newline at end of file
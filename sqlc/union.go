@@ -4,29 +4,83 @@ import (
 	"fmt"
 )
 
-type Union_query struct {
-	Select_query
-	unions			[]*Select_query
-	all				bool
-}
+const (
+	set_union			= "UNION"
+	set_union_all		= "UNION ALL"
+	set_intersect		= "INTERSECT"
+	set_intersect_all	= "INTERSECT ALL"
+	set_except			= "EXCEPT"
+	set_except_all		= "EXCEPT ALL"
+)
+
+type (
+	Union_query struct {
+		Select_query
+		members			[]set_member
+		all				bool
+	}
+	
+	//	Member of a set operation: *Select_query or a nested *Union_query
+	Set_query interface {
+		compile(ctx *compiler, inner_condition func(ctx *compiler, first *bool)) error
+		set_parentheses() bool
+		set_outputs() []select_field
+		set_validate(schema Schema, errs *validate_errors)
+	}
+	
+	set_member struct {
+		operator		string
+		query			Set_query
+	}
+)
 
 func Union() *Union_query {
 	return &Union_query{
 		//	Pre-allocation with 2 queries
-		unions:	make([]*Select_query, 0, 2),
+		members:	make([]set_member, 0, 2),
 	}
 }
 
 func Union_all() *Union_query {
 	return &Union_query{
 		//	Pre-allocation with 2 queries
-		unions:	make([]*Select_query, 0, 2),
-		all:	true,
+		members:	make([]set_member, 0, 2),
+		all:		true,
 	}
 }
 
-func (q *Union_query) Union(query *Select_query) *Union_query {
-	q.unions = append(q.unions, query)
+func (q *Union_query) Union(query Set_query) *Union_query {
+	if q.all {
+		return q.member(set_union_all, query)
+	}
+	return q.member(set_union, query)
+}
+
+//	Rows of the members before that are also in the query
+func (q *Union_query) Intersect(query Set_query) *Union_query {
+	return q.member(set_intersect, query)
+}
+
+func (q *Union_query) Intersect_all(query Set_query) *Union_query {
+	return q.member(set_intersect_all, query)
+}
+
+//	Rows of the members before that are not in the query
+func (q *Union_query) Except(query Set_query) *Union_query {
+	return q.member(set_except, query)
+}
+
+func (q *Union_query) Except_all(query Set_query) *Union_query {
+	return q.member(set_except_all, query)
+}
+
+func (q *Union_query) member(operator string, query Set_query) *Union_query {
+	q.members = append(q.members, set_member{operator, query})
+	return q
+}
+
+func (q *Union_query) With(ctes ...*Cte) *Union_query {
+	q.Select_query.With(ctes...)
 	return q
 }
 
@@ -55,16 +109,46 @@ func (q *Union_query) Group(fields []string) *Union_query {
 	return q
 }
 
+func (q *Union_query) Having(clause *Where_clause) *Union_query {
+	q.Select_query.Having(clause)
+	return q
+}
+
+func (q *Union_query) Select_window(alias string, f *Window_func) *Union_query {
+	q.Select_query.Select_window(alias, f)
+	return q
+}
+
+func (q *Union_query) Window(name string, spec *Window_spec) *Union_query {
+	q.Select_query.Window(name, spec)
+	return q
+}
+
 func (q *Union_query) Order(fields []string) *Union_query {
 	q.Select_query.Order(fields)
 	return q
 }
 
-func (q *Union_query) Limit(offset uint32, limit uint8) *Union_query {
+func (q *Union_query) Limit(offset, limit uint32) *Union_query {
 	q.Select_query.Limit(offset, limit)
 	return q
 }
 
+func (q *Union_query) Seek(values ...any) *Union_query {
+	q.Select_query.Seek(values...)
+	return q
+}
+
+func (q *Union_query) Seek_before(values ...any) *Union_query {
+	q.Select_query.Seek_before(values...)
+	return q
+}
+
+func (q *Union_query) Cursor(token string) *Union_query {
+	q.Select_query.Cursor(token)
+	return q
+}
+
 func (q *Union_query) Compile() (string, []any, error){
 	ctx := compiler_pool.Get().(*compiler)
 	defer func() {
@@ -72,70 +156,164 @@ func (q *Union_query) Compile() (string, []any, error){
 		compiler_pool.Put(ctx)
 	}()
 	
-	if q.joined {
+	if err := q.compile(ctx, nil); err != nil {
+		return "", nil, err
+	}
+	ctx.sb.WriteByte('\n')
+	
+	return ctx.sb.String(), ctx.data, nil
+}
+
+func (q *Union_query) compile(ctx *compiler, inner_condition func(ctx *compiler, first *bool)) error {
+	if err := q.seek_error(); err != nil {
+		return err
+	}
+	if err := q.window_error(); err != nil {
+		return err
+	}
+	
+	if q.joined || q.where_clause.correlated() {
 		ctx.use_alias = true
 	}
 	
 	var err error
+	if err = q.compile_with(ctx); err != nil {
+		return err
+	}
+	
+	//	Set operation without a derived table: ORDER BY and LIMIT apply to the result
+	if q.bare() && inner_condition == nil {
+		if err = q.compile_members(ctx); err != nil {
+			return err
+		}
+		q.compile_order(ctx)
+		q.compile_limit(ctx)
+		return nil
+	}
+	
 	if err = q.compile_tables(ctx, "t"); err != nil {
-		return "", nil, err
+		return err
 	}
 	
-	q.compile_select(ctx)
+	if err = q.compile_select(ctx); err != nil {
+		return err
+	}
 	if err = q.compile_from(ctx); err != nil {
-		return "", nil, err
+		return err
 	}
 	if err = q.compile_joins(ctx, nil); err != nil {
-		return "", nil, err
+		return err
 	}
-	if err = q.compile_where(ctx, nil); err != nil {
-		return "", nil, err
+	if err = q.compile_where(ctx, merge_conditions(q.seek_condition(), inner_condition)); err != nil {
+		return err
 	}
 	q.compile_group(ctx)
+	if err = q.compile_having(ctx); err != nil {
+		return err
+	}
+	q.compile_windows(ctx)
 	q.compile_order(ctx)
 	q.compile_limit(ctx)
-	ctx.sb.WriteByte('\n')
-	
-	return ctx.sb.String(), ctx.data, nil
+	return nil
 }
 
 func (q *Union_query) compile_from(ctx *compiler) error {
-	length := len(q.unions)
-	if length < 1 {
-		return fmt.Errorf("Must have at least two queries to union")
-	}
-	
-	sep := "UNION\n"
-	if q.all {
-		sep = "UNION ALL\n"
-	}
-	
 	//audit := Audit(sb, "union")
 	
 	//	Pre-allocation
-	alloc := 10 + len(q.t) + length * (alloc_query + len(sep))	//	"FROM (\n" + ") \n"
+	alloc := 10 + len(q.t)	//	"FROM (\n" + ") \n"
 	ctx.sb.Alloc(alloc)
 	//audit.Grow(alloc)
 	
 	ctx.sb.WriteString("FROM (\n")
+	if err := q.compile_members(ctx); err != nil {
+		return err
+	}
+	ctx.sb.WriteString(") ")
+	ctx.sb.WriteString(q.t)
+	ctx.sb.WriteByte('\n')
+	//audit.Audit()
+	return nil
+}
+
+//	Members are applied from left to right, but INTERSECT binds tighter than UNION and EXCEPT, so the members before an INTERSECT are put in parentheses
+func (q *Union_query) compile_members(ctx *compiler) error {
+	length := len(q.members)
+	if length < 1 {
+		return fmt.Errorf("Must have at least two queries to union")
+	}
 	
-	for i, query := range q.unions {
-		sql, data, err := query.Compile()
-		if err != nil {
-			return err
+	//	Pre-allocation
+	ctx.sb.Alloc(length * (alloc_query + 15))	//	"INTERSECT ALL\n" + "(\n" + ")\n"
+	
+	var lower bool
+	for _, m := range q.members[1:] {
+		if !intersect_operator(m.operator) {
+			lower = true
+		} else if lower {
+			ctx.sb.WriteString("(\n")
+			lower = false
 		}
-		
+	}
+	
+	lower = false
+	for i, m := range q.members {
 		if i > 0 {
-			ctx.sb.WriteString(sep)
+			if !intersect_operator(m.operator) {
+				lower = true
+			} else if lower {
+				ctx.sb.WriteString(")\n")
+				lower = false
+			}
+			ctx.sb.WriteString(m.operator)
+			ctx.sb.WriteByte('\n')
 		}
-		ctx.sb.WriteString(sql)
 		
-		ctx.append_data(data)
+		if !m.query.set_parentheses() {
+			if err := compile_subquery(ctx, m.query); err != nil {
+				return err
+			}
+			continue
+		}
+		ctx.sb.WriteString("(\n")
+		if err := compile_subquery(ctx, m.query); err != nil {
+			return err
+		}
+		ctx.sb.WriteString(")\n")
 	}
-	
-	ctx.sb.WriteString(") ")
-	ctx.sb.WriteString(q.t)
-	ctx.sb.WriteByte('\n')
-	//audit.Audit()
 	return nil
+}
+
+//	No outer select on the derived table
+func (q *Union_query) bare() bool {
+	return len(q.select_fields) == 0 && !q.joined && q.where_clause == nil && len(q.group) == 0 && q.having == nil && len(q.windows) == 0 && q.seek == nil
+}
+
+//	Nested sets are always in parentheses
+func (q *Union_query) set_parentheses() bool {
+	return true
+}
+
+func (q *Union_query) set_outputs() []select_field {
+	if len(q.select_fields) != 0 || len(q.members) == 0 {
+		return q.select_fields
+	}
+	return q.members[0].query.set_outputs()
+}
+
+//	ORDER BY and LIMIT of a member must be in parentheses
+func (q *Select_query) set_parentheses() bool {
+	return len(q.order) != 0 || q.limit.limit != 0 || q.seek != nil
+}
+
+func (q *Select_query) set_outputs() []select_field {
+	return q.select_fields
+}
+
+func (q *Select_query) set_validate(schema Schema, errs *validate_errors){
+	q.validate(schema, errs, "")
+}
+
+func intersect_operator(operator string) bool {
+	return operator == set_intersect || operator == set_intersect_all
 }
\ No newline at end of file
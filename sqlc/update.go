@@ -55,6 +55,11 @@ func (q *Update_query) Left_join(table, t, field, field_foreign string) *Update_
 	return q
 }
 
+func (q *Update_query) With(ctes ...*Cte) *Update_query {
+	q.with(ctes)
+	return q
+}
+
 func (q *Update_query) Where(clause *Where_clause) *Update_query {
 	q.where_clause = clause
 	return q
@@ -71,11 +76,14 @@ func (q *Update_query) Compile() (string, []any, error){
 		compiler_pool.Put(ctx)
 	}()
 	
-	if q.joined {
+	if q.joined || q.where_clause.correlated() {
 		ctx.use_alias = true
 	}
 	
 	var err error
+	if err = q.compile_with(ctx); err != nil {
+		return "", nil, err
+	}
 	t := q.base_table_short()
 	if err = q.compile_tables(ctx, t); err != nil {
 		return "", nil, err
@@ -92,16 +100,19 @@ func (q *Update_query) Compile() (string, []any, error){
 	ctx.sb.Alloc(alloc)
 	//audit.Grow(alloc)
 	
-	ctx.sb.WriteString("UPDATE .")
-	ctx.sb.WriteString(q.table)
+	ctx.sb.WriteString("UPDATE ")
+	q.write_optimizer_hints(ctx)
+	ctx.write_table(q.table)
 	if ctx.use_alias {
 		ctx.sb.WriteByte(' ')
 		ctx.sb.WriteString(q.t)
+		q.write_index_hints(ctx, "")
 		ctx.sb.WriteByte('\n')
 		if err = q.compile_joins(ctx, nil); err != nil {
 			return "", nil, err
 		}
 	} else {
+		q.write_index_hints(ctx, "")
 		ctx.sb.WriteByte('\n')
 	}
 	ctx.sb.WriteString("SET ")
@@ -130,9 +141,9 @@ func (q *Update_query) compile_fields(ctx *compiler) error {
 			ctx.sb.WriteString(", ")
 		}
 		
-		q.write_update_field(ctx, entry.field, entry.operator)
-		
-		ctx.append_data(entry.value)
+		if err := q.write_update_field(ctx, entry.field, entry.operator, entry.value); err != nil {
+			return err
+		}
 		unique[entry.field]	= struct{}{}
 	}
 	return nil
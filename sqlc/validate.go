@@ -0,0 +1,487 @@
+package sqlc
+
+import (
+	"errors"
+	"strings"
+)
+
+type (
+	Schema interface {
+		Exists_table(table string) bool
+		Exists_column(table, column string) bool
+	}
+	
+	Validator interface {
+		Validate(schema Schema) error
+	}
+	
+	validate_scope struct {
+		schema		Schema
+		errs		*validate_errors
+		table		string
+		root_table	string
+		tables		map[string]string	//	Table alias => table
+		outputs		map[string]struct{}	//	Select aliases (valid in GROUP/ORDER)
+		derived		bool				//	Base table is a derived table (union)
+		subqueries	map[string]map[string]struct{}	//	Join subquery alias => output columns (nil: any column)
+	}
+	
+	validate_errors struct {
+		list		[]error
+		seen		map[string]struct{}
+	}
+)
+
+func (q *Select_query) Validate(schema Schema) error {
+	errs := &validate_errors{}
+	q.validate(schema, errs, "")
+	return errs.join()
+}
+
+func (q *Update_query) Validate(schema Schema) error {
+	errs	:= &validate_errors{}
+	s		:= q.validate_scope(q.validate_with(schema, errs), errs, "")
+	if q.fields != nil {
+		for _, entry := range q.fields.entries {
+			s.column(entry.field)
+		}
+	}
+	q.validate_where(s)
+	return errs.join()
+}
+
+func (q *Delete_query) Validate(schema Schema) error {
+	errs	:= &validate_errors{}
+	s		:= q.validate_scope(q.validate_with(schema, errs), errs, "")
+	q.validate_where(s)
+	return errs.join()
+}
+
+func (q *Insert_query) Validate(schema Schema) error {
+	errs	:= &validate_errors{}
+	s		:= q.validate_scope(schema, errs, "")
+	if q.fields != nil {
+		for _, entry := range q.fields.entries {
+			s.column(entry.field)
+		}
+	}
+	for _, field := range q.update_duplicate_fields {
+		s.column(field)
+	}
+	return errs.join()
+}
+
+func (q *Inserts_query) Validate(schema Schema) error {
+	errs	:= &validate_errors{}
+	s		:= q.validate_scope(schema, errs, "")
+	for _, field := range q.col_keys {
+		s.column(field)
+	}
+	for _, field := range q.update_dublicate_fields {
+		s.column(field)
+	}
+	return errs.join()
+}
+
+func (q *Union_query) Validate(schema Schema) error {
+	errs := &validate_errors{}
+	q.set_validate(schema, errs)
+	return errs.join()
+}
+
+func (q *Union_query) set_validate(schema Schema, errs *validate_errors){
+	//	Columns of the derived table are the output of the union queries
+	schema	= q.validate_with(schema, errs)
+	outputs	:= map[string]struct{}{}
+	for i, m := range q.members {
+		m.query.set_validate(schema, errs)
+		if i == 0 {
+			for _, f := range m.query.set_outputs() {
+				outputs[select_output_name(f)] = struct{}{}
+			}
+		}
+	}
+	
+	s := &validate_scope{
+		schema:		schema,
+		errs:		errs,
+		tables:		map[string]string{},
+		outputs:	outputs,
+		derived:	true,
+	}
+	q.validate_joins(s)
+	for _, f := range q.select_fields {
+		if f.window != nil {
+			s.window_func(f.window)
+		} else if f.function != SELECT_RAW {
+			s.column(select_field_column(f.field))
+		}
+	}
+	s.where(q.where_clause)
+	for _, f := range q.group {
+		s.column(order_field(f))
+	}
+	s.having(q.having)
+	for _, w := range q.windows {
+		s.window(w.spec)
+	}
+	for _, f := range q.order {
+		s.column(order_field(f))
+	}
+}
+
+func (q *Select_query) validate(schema Schema, errs *validate_errors, root_table string){
+	schema = q.validate_with(schema, errs)
+	s := q.validate_scope(schema, errs, root_table)
+	
+	for _, f := range q.select_fields {
+		if f.alias != "" {
+			s.outputs[f.alias] = struct{}{}
+		} else if pos := strings.IndexByte(f.field, ' '); pos != -1 {
+			s.outputs[f.field[pos+1:]] = struct{}{}
+		}
+		if f.window != nil {
+			s.window_func(f.window)
+			continue
+		}
+		if f.subquery != nil {
+			s.correlated_subquery(f.subquery)
+			continue
+		}
+		if f.match != nil {
+			s.columns(f.match.fields)
+			continue
+		}
+		if f.extract != nil {
+			s.column(f.extract.field)
+			continue
+		}
+		if f.function == SELECT_RAW {
+			continue
+		}
+		s.column(select_field_column(f.field))
+	}
+	
+	for _, sj := range q.select_jsons {
+		s.outputs[sj.select_field] = struct{}{}
+		if sj.query == nil {
+			continue
+		}
+		sj.query.validate(schema, errs, s.root_table)
+		inner := sj.query.validate_scope(schema, errs, s.root_table)
+		inner.column(sj.key)
+		for _, f := range sj.order {
+			inner.column(order_field(f))
+		}
+		if sj.inner_field != "" {
+			inner.column(sj.inner_field)
+			s.column(sj.outer_field)
+		}
+	}
+	
+	q.validate_where(s)
+	for _, f := range q.group {
+		s.output_or_column(order_field(f))
+	}
+	s.having(q.having)
+	for _, w := range q.windows {
+		s.window(w.spec)
+	}
+	for _, f := range q.order {
+		s.output_or_column(order_field(f))
+	}
+}
+
+func (q *query_join) validate_scope(schema Schema, errs *validate_errors, root_table string) *validate_scope {
+	s := &validate_scope{
+		schema:		schema,
+		errs:		errs,
+		table:		q.table,
+		root_table:	root_table,
+		tables:		make(map[string]string, len(q.joins)),
+		outputs:	map[string]struct{}{},
+	}
+	if s.root_table == "" {
+		s.root_table = q.table
+	}
+	
+	if !schema.Exists_table(q.table) {
+		errs.add("Unknown table: "+q.table)
+	}
+	q.validate_joins(s)
+	return s
+}
+
+func (q *query_join) validate_joins(s *validate_scope){
+	for _, j := range q.joins {
+		s.tables[j.t] = j.table
+	}
+	for _, j := range q.joins {
+		if j.query != nil {
+			s.subquery(j)
+			continue
+		}
+		if !s.schema.Exists_table(j.table) {
+			s.errs.add("Unknown table: "+j.table)
+			continue
+		}
+		for _, on := range j.on {
+			s.table_column(j.table, on.Field)
+			s.join_foreign(on)
+		}
+	}
+}
+
+//	Join on a subquery: Columns are the output of the subquery
+func (s *validate_scope) subquery(j join){
+	j.query.validate(s.schema, s.errs, "")
+	
+	if s.subqueries == nil {
+		s.subqueries = map[string]map[string]struct{}{}
+	}
+	s.subqueries[j.t] = select_output_columns(j.query)
+	
+	for _, on := range j.on {
+		s.subquery_column(j.t, on.Field)
+		s.join_foreign(on)
+	}
+}
+
+func (s *validate_scope) join_foreign(on Join_condition){
+	if !on.Fixed_value {
+		s.column(on.Field_foreign)
+		return
+	}
+	if col, ok := on.Field_value.(Col); ok {
+		s.column(string(col))
+	}
+}
+
+func (s *validate_scope) subquery_column(alias, column string){
+	columns := s.subqueries[alias]
+	if columns == nil || column == "*" {
+		return
+	}
+	if _, ok := columns[column]; !ok {
+		s.errs.add("Unknown column: "+alias+"."+column)
+	}
+}
+
+func (q *query_where) validate_where(s *validate_scope){
+	if q.use_id {
+		s.column("id")
+	}
+	s.where(q.where_clause)
+}
+
+func (s *validate_scope) where(clause *Where_clause){
+	if clause == nil {
+		return
+	}
+	s.where(clause.wrapped)
+	for _, group := range clause.or_groups {
+		s.where(group)
+	}
+	for _, node := range clause.nodes {
+		s.where(node)
+	}
+	for _, condition := range clause.conditions {
+		switch v := condition.value.(type) {
+		case *correlated_subquery:
+			s.correlated_subquery(v)
+			continue
+		case *match_against:
+			s.columns(v.fields)
+			continue
+		case *where_raw:
+			continue
+		case *json_condition:
+			s.column(v.field)
+			continue
+		}
+		s.column(condition.field)
+		switch v := condition.value.(type) {
+		case Col:
+			s.column(string(v))
+		case *Select_query:
+			v.validate(s.schema, s.errs, "")
+		case *where_subquery:
+			v.query.validate(s.schema, s.errs, "")
+		}
+	}
+}
+
+//	Aggregate functions ("func|field") or select aliases
+func (s *validate_scope) having(clause *Where_clause){
+	if clause == nil {
+		return
+	}
+	s.having(clause.wrapped)
+	for _, group := range clause.or_groups {
+		s.having(group)
+	}
+	for _, node := range clause.nodes {
+		s.having(node)
+	}
+	for _, condition := range clause.conditions {
+		if col, ok := condition.value.(Col); ok {
+			s.having_field(string(col))
+		}
+		s.having_field(condition.field)
+	}
+}
+
+func (s *validate_scope) having_field(field string){
+	if pos := strings.IndexByte(field, '|'); pos != -1 {
+		if field[:pos] != SELECT_RAW {
+			s.column(field[pos+1:])
+		}
+		return
+	}
+	s.output_or_column(field)
+}
+
+func (s *validate_scope) correlated_subquery(c *correlated_subquery){
+	c.query.validate(s.schema, s.errs, "")
+	if c.inner_field != "" {
+		c.query.validate_scope(s.schema, s.errs, "").column(c.inner_field)
+		s.column(c.outer_field)
+	}
+}
+
+func (s *validate_scope) window_func(f *Window_func){
+	s.column(f.field)
+	s.window(f.spec)
+}
+
+func (s *validate_scope) window(spec *Window_spec){
+	if spec == nil {
+		return
+	}
+	for _, f := range spec.partition {
+		s.column(f)
+	}
+	for _, f := range spec.order {
+		s.column(order_field(f))
+	}
+}
+
+//	Field in the query scope: "field", "t.field" or "<root>.field"
+func (s *validate_scope) column(field string){
+	if field == "" || field == "*" {
+		return
+	}
+	if pos := strings.IndexByte(field, '.'); pos != -1 {
+		alias := field[:pos]
+		if s.derived {
+			if _, ok := s.tables[alias]; !ok {
+				//	Derived table alias
+				s.derived_column(field[pos+1:])
+				return
+			}
+		}
+		if alias == ROOT_ALIAS {
+			s.table_column(s.root_table, field[pos+1:])
+			return
+		}
+		if _, ok := s.subqueries[alias]; ok {
+			s.subquery_column(alias, field[pos+1:])
+			return
+		}
+		table, ok := s.tables[alias]
+		if !ok {
+			s.errs.add("Unknown table alias: "+alias+" ("+field+")")
+			return
+		}
+		s.table_column(table, field[pos+1:])
+		return
+	}
+	if s.derived {
+		s.derived_column(field)
+		return
+	}
+	s.table_column(s.table, field)
+}
+
+func (s *validate_scope) columns(fields []string){
+	for _, field := range fields {
+		s.column(field)
+	}
+}
+
+func (s *validate_scope) output_or_column(field string){
+	if _, ok := s.outputs[field]; ok {
+		return
+	}
+	s.column(field)
+}
+
+//	Field in a derived table
+func (s *validate_scope) derived_column(field string){
+	if _, ok := s.outputs[field]; !ok {
+		s.errs.add("Unknown column: "+field)
+	}
+}
+
+func (s *validate_scope) table_column(table, column string){
+	if column == "*" || !s.schema.Exists_table(table) {
+		return
+	}
+	if !s.schema.Exists_column(table, column) {
+		s.errs.add("Unknown column: "+table+"."+column)
+	}
+}
+
+func (e *validate_errors) add(msg string){
+	if e.seen == nil {
+		e.seen = map[string]struct{}{}
+	}
+	if _, ok := e.seen[msg]; ok {
+		return
+	}
+	e.seen[msg]	= struct{}{}
+	e.list		= append(e.list, errors.New(msg))
+}
+
+func (e *validate_errors) join() error {
+	return errors.Join(e.list...)
+}
+
+//	Strip "field alias" select syntax
+func select_field_column(field string) string {
+	if pos := strings.IndexByte(field, ' '); pos != -1 {
+		return field[:pos]
+	}
+	return field
+}
+
+//	Strip direction in "field DESC"
+func order_field(field string) string {
+	return select_field_column(field)
+}
+
+//	Output columns of a subquery (nil: any column with "SELECT *")
+func select_output_columns(q *Select_query) map[string]struct{} {
+	if len(q.select_fields) == 0 {
+		return nil
+	}
+	columns := make(map[string]struct{}, len(q.select_fields))
+	for _, f := range q.select_fields {
+		columns[select_output_name(f)] = struct{}{}
+	}
+	return columns
+}
+
+func select_output_name(f select_field) string {
+	if f.alias != "" {
+		return f.alias
+	}
+	field := f.field
+	if pos := strings.IndexByte(field, ' '); pos != -1 {
+		return field[pos+1:]
+	}
+	if pos := strings.IndexByte(field, '.'); pos != -1 {
+		return field[pos+1:]
+	}
+	return field
+}
\ No newline at end of file
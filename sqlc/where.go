@@ -1,6 +1,11 @@
 package sqlc
 
-import "slices"
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
 
 const (
 	Op_eq Operator = iota
@@ -16,8 +21,19 @@ const (
 	Op_in
 	Op_not_in
 	Op_in_subquery
+	Op_not_in_subquery
+	Op_exists
+	Op_not_exists
+	Op_like
+	Op_not_like
+	Op_regexp
+	Op_not_regexp
+	Op_match
+	Op_raw
+	Op_json
 	
 	sql_op_bt		= "BETWEEN ? AND ?"
+	sql_like_escape	= " ESCAPE '!'"	//	Not a backslash (string literal differs with NO_BACKSLASH_ESCAPES)
 )
 
 var sql_ops = [...]string{
@@ -27,21 +43,56 @@ var sql_ops = [...]string{
 	Op_gteq:	">=",
 	Op_lt:		"<",
 	Op_lteq:	"<=",
+	
+	//	Operator names in errors
+	Op_null:			"IS NULL",
+	Op_not_null:		"IS NOT NULL",
+	Op_bt:				"BETWEEN",
+	Op_not_bt:			"NOT BETWEEN",
+	Op_in:				"IN",
+	Op_not_in:			"NOT IN",
+	Op_in_subquery:		"IN",
+	Op_not_in_subquery:	"NOT IN",
+	Op_exists:			"EXISTS",
+	Op_not_exists:		"NOT EXISTS",
+	
+	Op_like:		"LIKE",
+	Op_not_like:	"NOT LIKE",
+	Op_regexp:		"REGEXP",
+	Op_not_regexp:	"NOT REGEXP",
+	Op_match:		"MATCH",
+	Op_raw:			"RAW",
+	Op_json:		"JSON",
 }
 
 type (
 	Operator			uint8
 	
+	//	LIKE pattern with escaped user input
+	like_escape			string
+	
+	//	Field as value in conditions: Gt("updated", Col("synced"))
+	Col					string
+	
 	Where_clause struct {
 		wrapped			*Where_clause
 		or_groups		[]*Where_clause
 		conditions		[]where_condition
+		nodes			[]*Where_clause	//	Expression nodes: And(), Or() and Not()
+		or				bool			//	Terms are joined with OR
+		not				bool
 		
 		num				int
 		alloc			int
 		alloc_data		int
 	}
 	
+	//	Raw SQL condition with positional args
+	where_raw struct {
+		sql				string
+		args			[]any
+	}
+	
 	where_condition struct {
 		field			string
 		operator		Operator
@@ -66,6 +117,48 @@ func (w *Where_clause) Or_group(where *Where_clause) *Where_clause {
 	return w
 }
 
+//	Expression node with clauses joined by AND: "(a AND b)"
+func And(clauses ...*Where_clause) *Where_clause {
+	return &Where_clause{
+		nodes:	clauses,
+	}
+}
+
+//	Expression node with clauses joined by OR: "(a OR b)"
+func Or(clauses ...*Where_clause) *Where_clause {
+	return &Where_clause{
+		nodes:	clauses,
+		or:		true,
+	}
+}
+
+//	Negated expression node: "NOT (a AND b)"
+func Not(clause *Where_clause) *Where_clause {
+	return &Where_clause{
+		nodes:	[]*Where_clause{clause},
+		or:		clause != nil && clause.or,
+		not:	true,
+	}
+}
+
+//	Add an And() node to the clause
+func (w *Where_clause) And(clauses ...*Where_clause) *Where_clause {
+	w.nodes = append(w.nodes, And(clauses...))
+	return w
+}
+
+//	Add an Or() node to the clause
+func (w *Where_clause) Or(clauses ...*Where_clause) *Where_clause {
+	w.nodes = append(w.nodes, Or(clauses...))
+	return w
+}
+
+//	Add a Not() node to the clause
+func (w *Where_clause) Not(clause *Where_clause) *Where_clause {
+	w.nodes = append(w.nodes, Not(clause))
+	return w
+}
+
 func (w *Where_clause) Eq(field string, value any) *Where_clause {
 	w.clause(field, Op_eq, value)
 	return w
@@ -146,50 +239,291 @@ func (w *Where_clause) In_subquery(field string, query *Select_query) *Where_cla
 	return w
 }
 
-func write_operator_condition(sb *sbuilder, operator Operator, value any) ([]any, error){
-	var subquery_data []any
+//	Field not in the rows of the subquery: "x NOT IN (SELECT ...)"
+func (w *Where_clause) Not_in_subquery(field string, query *Select_query) *Where_clause {
+	w.clause(field, Op_not_in_subquery, query)
+	return w
+}
+
+func (w *Where_clause) Like(field, pattern string) *Where_clause {
+	w.clause(field, Op_like, pattern)
+	return w
+}
+
+func (w *Where_clause) Not_like(field, pattern string) *Where_clause {
+	w.clause(field, Op_not_like, pattern)
+	return w
+}
+
+func (w *Where_clause) Regexp(field, pattern string) *Where_clause {
+	w.clause(field, Op_regexp, pattern)
+	return w
+}
+
+func (w *Where_clause) Not_regexp(field, pattern string) *Where_clause {
+	w.clause(field, Op_not_regexp, pattern)
+	return w
+}
+
+//	"x LIKE '%value%'" (wildcards in value are escaped)
+func (w *Where_clause) Contains(field, value string) *Where_clause {
+	w.clause(field, Op_like, like_escape("%"+escape_like(value)+"%"))
+	return w
+}
+
+//	"x LIKE 'value%'" (wildcards in value are escaped)
+func (w *Where_clause) Starts_with(field, value string) *Where_clause {
+	w.clause(field, Op_like, like_escape(escape_like(value)+"%"))
+	return w
+}
+
+//	"x LIKE '%value'" (wildcards in value are escaped)
+func (w *Where_clause) Ends_with(field, value string) *Where_clause {
+	w.clause(field, Op_like, like_escape("%"+escape_like(value)))
+	return w
+}
+
+func (w *Where_clause) Eq_subquery(field string, query *Select_query) *Where_clause {
+	w.clause(field, Op_eq, &where_subquery{query: query})
+	return w
+}
+
+func (w *Where_clause) Not_eq_subquery(field string, query *Select_query) *Where_clause {
+	w.clause(field, Op_not_eq, &where_subquery{query: query})
+	return w
+}
+
+func (w *Where_clause) Gt_subquery(field string, query *Select_query) *Where_clause {
+	w.clause(field, Op_gt, &where_subquery{query: query})
+	return w
+}
+
+func (w *Where_clause) Gt_eq_subquery(field string, query *Select_query) *Where_clause {
+	w.clause(field, Op_gteq, &where_subquery{query: query})
+	return w
+}
+
+func (w *Where_clause) Lt_subquery(field string, query *Select_query) *Where_clause {
+	w.clause(field, Op_lt, &where_subquery{query: query})
+	return w
+}
+
+func (w *Where_clause) Lt_eq_subquery(field string, query *Select_query) *Where_clause {
+	w.clause(field, Op_lteq, &where_subquery{query: query})
+	return w
+}
+
+//	Comparison with any row of the subquery: "x>ANY (SELECT ...)"
+func (w *Where_clause) Any(field string, operator Operator, query *Select_query) *Where_clause {
+	w.clause(field, operator, &where_subquery{query, quantifier_any})
+	return w
+}
+
+//	Comparison with all rows of the subquery: "x>ALL (SELECT ...)"
+func (w *Where_clause) All(field string, operator Operator, query *Select_query) *Where_clause {
+	w.clause(field, operator, &where_subquery{query, quantifier_all})
+	return w
+}
+
+func (w *Where_clause) Exists(query *Select_query) *Where_clause {
+	w.clause("", Op_exists, &correlated_subquery{
+		query:	query,
+	})
+	return w
+}
+
+func (w *Where_clause) Not_exists(query *Select_query) *Where_clause {
+	w.clause("", Op_not_exists, &correlated_subquery{
+		query:	query,
+	})
+	return w
+}
+
+//	Correlated subquery: Inner field (subquery) equals outer field
+func (w *Where_clause) Exists_condition(query *Select_query, inner_field, outer_field string) *Where_clause {
+	w.clause("", Op_exists, &correlated_subquery{
+		query:			query,
+		inner_field:	inner_field,
+		outer_field:	outer_field,
+	})
+	return w
+}
+
+//	Correlated subquery: Inner field (subquery) equals outer field
+func (w *Where_clause) Not_exists_condition(query *Select_query, inner_field, outer_field string) *Where_clause {
+	w.clause("", Op_not_exists, &correlated_subquery{
+		query:			query,
+		inner_field:	inner_field,
+		outer_field:	outer_field,
+	})
+	return w
+}
+
+//	Raw SQL condition: "DATE(<root>.created)=? AND x & ? > 0"
+func (w *Where_clause) Raw(sql string, args ...any) *Where_clause {
+	w.clause("", Op_raw, &where_raw{sql, args})
+	return w
+}
+
+//	Raw SQL is written in parentheses with "<root>" replaced by the root table alias
+func write_raw_condition(ctx *compiler, r *where_raw) error {
+	positions := raw_placeholders(r.sql)
+	if len(positions) != len(r.args) {
+		return fmt.Errorf("Raw where condition has %d placeholders and %d args: %s", len(positions), len(r.args), r.sql)
+	}
+	sql, args, err := expand_raw_args(r.sql, positions, r.args)
+	if err != nil {
+		return err
+	}
+	ctx.sb.WriteByte('(')
+	if !strings.Contains(sql, ROOT_ALIAS) {
+		ctx.sb.WriteString(sql)
+	} else if ctx.use_alias {
+		ctx.sb.WriteString(strings.ReplaceAll(sql, ROOT_ALIAS+".", ctx.root_t+"."))
+	} else {
+		ctx.sb.WriteString(strings.ReplaceAll(sql, ROOT_ALIAS+".", ""))
+	}
+	ctx.sb.WriteByte(')')
+	ctx.data = append(ctx.data, args...)
+	return nil
+}
+
+//	Slice args (except []byte) are expanded to a placeholder list: "x IN (?)" => "x IN (?, ?)"
+func expand_raw_args(sql string, positions []int, args []any) (string, []any, error){
+	if !slices.ContainsFunc(args, raw_list) {
+		return sql, args, nil
+	}
+	
+	var sb strings.Builder
+	list := make([]any, 0, len(args))
+	start := 0
+	for i, pos := range positions {
+		sb.WriteString(sql[start:pos])
+		start = pos + 1
+		if !raw_list(args[i]) {
+			sb.WriteByte('?')
+			list = append(list, args[i])
+			continue
+		}
+		v := reflect.ValueOf(args[i])
+		if v.Len() == 0 {
+			return "", nil, fmt.Errorf("Raw where condition arg %d is an empty list: %s", i+1, sql)
+		}
+		for j := range v.Len() {
+			if j > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteByte('?')
+			list = append(list, v.Index(j).Interface())
+		}
+	}
+	sb.WriteString(sql[start:])
+	return sb.String(), list, nil
+}
+
+func raw_list(arg any) bool {
+	t := reflect.TypeOf(arg)
+	return t != nil && t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8
+}
+
+//	Positions of placeholders outside quoted strings and identifiers
+func raw_placeholders(sql string) []int {
+	var (
+		positions	[]int
+		quote		byte
+	)
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote != '`' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '?':
+			positions = append(positions, i)
+		}
+	}
+	return positions
+}
+
+func write_col_operator(ctx *compiler, operator Operator, col Col) error {
+	if operator > Op_lteq {
+		return fmt.Errorf("Invalid operator for field comparison: %s", col)
+	}
+	ctx.sb.WriteString(sql_ops[operator])
+	return nil
+}
+
+//	Write operator and apply data: Field values (Col) are written with table alias t
+func write_operator_condition(ctx *compiler, t string, operator Operator, value any) error {
+	switch v := value.(type) {
+	case *where_subquery:
+		return write_where_subquery(ctx, operator, v)
+	case Col:
+		if err := write_col_operator(ctx, operator, v); err != nil {
+			return err
+		}
+		ctx.write_field(t, string(v))
+		return nil
+	}
 	
 	switch operator {
 	case Op_null:
-		sb.WriteString(" IS NULL")
+		ctx.sb.WriteString(" IS NULL")
+		return nil
 		
 	case Op_not_null:
-		sb.WriteString(" IS NOT NULL")
+		ctx.sb.WriteString(" IS NOT NULL")
+		return nil
 		
 	case Op_bt, Op_not_bt:
 		if operator == Op_not_bt {
-			sb.WriteString(" NOT")
+			ctx.sb.WriteString(" NOT")
 		}
-		sb.WriteByte(' ')
-		sb.WriteString(sql_op_bt)
+		ctx.sb.WriteByte(' ')
+		ctx.sb.WriteString(sql_op_bt)
 		
 	case Op_in, Op_not_in:
 		if operator == Op_not_in {
-			sb.WriteString(" NOT")
+			ctx.sb.WriteString(" NOT")
 		}
-		sb.WriteString(" IN (")
-		field_placeholder_list(len(value.([]any)), sb)
-		sb.WriteByte(')')
+		ctx.sb.WriteString(" IN (")
+		field_placeholder_list(len(value.([]any)), &ctx.sb)
+		ctx.sb.WriteByte(')')
 		
-	case Op_in_subquery:
-		var (
-			err error
-			sql	string
-		)
-		sql, subquery_data, err = value.(*Select_query).Compile()
-		if err != nil {
-			return nil, err
+	case Op_like, Op_not_like, Op_regexp, Op_not_regexp:
+		ctx.sb.WriteByte(' ')
+		ctx.sb.WriteString(sql_ops[operator])
+		ctx.sb.WriteString(" ?")
+		if v, ok := value.(like_escape); ok {
+			ctx.sb.WriteString(sql_like_escape)
+			ctx.append_data(string(v))
+			return nil
 		}
-		sb.WriteString(" IN (\n")
-		sb.WriteString(sql)
-		sb.WriteByte(')')
+		
+	case Op_in_subquery, Op_not_in_subquery:
+		if operator == Op_not_in_subquery {
+			ctx.sb.WriteString(" NOT")
+		}
+		//	Subquery data is applied when compiled
+		ctx.sb.WriteString(" IN (\n")
+		if err := compile_subquery(ctx, value.(*Select_query)); err != nil {
+			return err
+		}
+		ctx.sb.WriteByte(')')
+		return nil
 		
 	default:
-		sb.WriteString(sql_ops[operator])
-		sb.WriteByte('?')
+		ctx.sb.WriteString(sql_ops[operator])
+		ctx.sb.WriteByte('?')
 	}
 	
-	return subquery_data, nil
+	ctx.append_data(value)
+	return nil
 }
 
 func (w *Where_clause) clause(field string, operator Operator, value any){
@@ -213,20 +547,60 @@ func (w *Where_clause) clause(field string, operator Operator, value any){
 		}
 		
 	case Op_in, Op_not_in:
-		alloc_data		= len(value.([]any))
+		//	Invalid operators for subqueries (Any/All) are returned when compiled
+		values, _		:= value.([]any)
+		alloc_data		= len(values)
 		alloc			= 6 + alloc_field_placeholder_list(alloc_data)
 		if operator == Op_not_in {
 			alloc		+= 4
 		}
 		
-	case Op_in_subquery:
+	case Op_in_subquery, Op_not_in_subquery:
 		alloc			= 7 + alloc_query
+		if operator == Op_not_in_subquery {
+			alloc		+= 4
+		}
+		
+	case Op_exists, Op_not_exists:
+		alloc			= 10 + alloc_query
+		if operator == Op_not_exists {
+			alloc		+= 4
+		}
+		
+	case Op_match:
+		alloc_data		= 1
+		alloc			= 30 + len(value.(*match_against).fields) * alloc_field
+		
+	case Op_json:
+		alloc_data		= 2
+		alloc			= 40 + len(value.(*json_condition).field)
+		
+	case Op_raw:
+		r				:= value.(*where_raw)
+		alloc_data		= len(r.args)
+		alloc			= 2 + len(r.sql)
+		
+	case Op_like, Op_not_like, Op_regexp, Op_not_regexp:
+		alloc_data		= 1
+		alloc			= 3 + len(sql_ops[operator])
+		if _, ok := value.(like_escape); ok {
+			alloc		+= len(sql_like_escape)
+		}
 		
 	default:
 		alloc_data		= 1
 		alloc			= 1 + len(sql_ops[operator])
 	}
 	
+	switch v := value.(type) {
+	case *where_subquery:
+		alloc_data		= 0
+		alloc			+= 7 + alloc_query	//	"ANY (\n" + ")"
+	case Col:
+		alloc_data		= 0
+		alloc			+= len(v) + 3
+	}
+	
 	w.conditions = append(w.conditions, where_condition{
 		field:		field,
 		operator:	operator,
@@ -237,6 +611,23 @@ func (w *Where_clause) clause(field string, operator Operator, value any){
 	w.alloc_data	+= alloc_data
 }
 
+//	Escape wildcards "%" and "_" and the escape char "!"
+func escape_like(s string) string {
+	if !strings.ContainsAny(s, "%_!") {
+		return s
+	}
+	var sb strings.Builder
+	sb.Grow(len(s) + 4)
+	for i := range len(s) {
+		switch s[i] {
+		case '%', '_', '!':
+			sb.WriteByte('!')
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
 func (w *Where_clause) get_alloc() (num, alloc, alloc_data int){
 	num			= w.num
 	alloc		= w.alloc
@@ -255,9 +646,57 @@ func (w *Where_clause) get_alloc() (num, alloc, alloc_data int){
 			alloc_data	+= ad
 		}
 	}
+	for _, node := range w.nodes {
+		if node != nil {
+			n, a, ad := node.get_alloc()
+			num			+= n
+			alloc		+= a + 6	//	"NOT ()"
+			alloc_data	+= ad
+		}
+	}
 	return num, alloc, alloc_data
 }
 
+//	Clause has no conditions in any node
+func (w *Where_clause) empty() bool {
+	if w == nil {
+		return true
+	}
+	if len(w.conditions) != 0 || !w.wrapped.empty() {
+		return false
+	}
+	for _, group := range w.or_groups {
+		if !group.empty() {
+			return false
+		}
+	}
+	for _, node := range w.nodes {
+		if !node.empty() {
+			return false
+		}
+	}
+	return true
+}
+
+//	Number of terms joined in the clause scope
+func (w *Where_clause) terms() int {
+	n := len(w.conditions)
+	if !w.wrapped.empty() {
+		n++
+	}
+	for _, group := range w.or_groups {
+		if !group.empty() {
+			n++
+		}
+	}
+	for _, node := range w.nodes {
+		if !node.empty() {
+			n++
+		}
+	}
+	return n
+}
+
 func (w *Where_clause) collect_aliases(list alias_collect) error {
 	if w == nil {
 		return nil
@@ -275,7 +714,30 @@ func (w *Where_clause) collect_aliases(list alias_collect) error {
 			}
 		}
 	}
+	for _, node := range w.nodes {
+		if err = node.collect_aliases(list); err != nil {
+			return err
+		}
+	}
 	for _, f := range w.conditions {
+		switch v := f.value.(type) {
+		case *correlated_subquery:
+			list.apply(v.outer_field)
+			continue
+		case *match_against:
+			for _, field := range v.fields {
+				list.apply(field)
+			}
+			continue
+		case *where_raw:
+			list.apply_raw(v.sql)
+			continue
+		case *json_condition:
+			list.apply(v.field)
+			continue
+		case Col:
+			list.apply(string(v))
+		}
 		list.apply(f.field)
 	}
 	return nil
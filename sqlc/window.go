@@ -0,0 +1,318 @@
+package sqlc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	Unbounded_preceding	Frame_bound = "UNBOUNDED PRECEDING"
+	Unbounded_following	Frame_bound = "UNBOUNDED FOLLOWING"
+	Current_row			Frame_bound = "CURRENT ROW"
+	
+	frame_rows			= "ROWS"
+	frame_range			= "RANGE"
+)
+
+type (
+	Frame_bound			string
+	
+	Window_func struct {
+		function		string
+		field			string
+		offset			uint32
+		value			any
+		use_value		bool
+		spec			*Window_spec
+		name			string	//	Named window (WINDOW clause)
+	}
+	
+	Window_spec struct {
+		partition		[]string
+		order			[]string
+		frame			string
+		frame_start		Frame_bound
+		frame_end		Frame_bound
+	}
+	
+	select_window struct {
+		name			string
+		spec			*Window_spec
+	}
+)
+
+func Row_number() *Window_func {
+	return &Window_func{function: "ROW_NUMBER"}
+}
+
+func Rank() *Window_func {
+	return &Window_func{function: "RANK"}
+}
+
+func Dense_rank() *Window_func {
+	return &Window_func{function: "DENSE_RANK"}
+}
+
+//	Value of the field in the row offset rows before the current row
+func Lag(field string, offset uint32) *Window_func {
+	return &Window_func{
+		function:	"LAG",
+		field:		field,
+		offset:		offset,
+	}
+}
+
+//	Value of the field in the row offset rows after the current row
+func Lead(field string, offset uint32) *Window_func {
+	return &Window_func{
+		function:	"LEAD",
+		field:		field,
+		offset:		offset,
+	}
+}
+
+func Sum(field string) *Window_func {
+	return &Window_func{
+		function:	"SUM",
+		field:		field,
+	}
+}
+
+func Avg(field string) *Window_func {
+	return &Window_func{
+		function:	"AVG",
+		field:		field,
+	}
+}
+
+//	Value when LAG/LEAD has no row at the offset
+func (f *Window_func) Default(value any) *Window_func {
+	f.value		= value
+	f.use_value	= true
+	return f
+}
+
+func (f *Window_func) Over(spec *Window_spec) *Window_func {
+	f.spec = spec
+	f.name = ""
+	return f
+}
+
+//	Use a named window defined with Window() on the query
+func (f *Window_func) Over_window(name string) *Window_func {
+	f.name = name
+	f.spec = nil
+	return f
+}
+
+func Window() *Window_spec {
+	return &Window_spec{}
+}
+
+func (w *Window_spec) Partition(fields ...string) *Window_spec {
+	w.partition = fields
+	return w
+}
+
+func (w *Window_spec) Order(fields ...string) *Window_spec {
+	w.order = fields
+	return w
+}
+
+func (w *Window_spec) Rows(start, end Frame_bound) *Window_spec {
+	w.frame			= frame_rows
+	w.frame_start	= start
+	w.frame_end		= end
+	return w
+}
+
+func (w *Window_spec) Range(start, end Frame_bound) *Window_spec {
+	w.frame			= frame_range
+	w.frame_start	= start
+	w.frame_end		= end
+	return w
+}
+
+func Preceding(n uint32) Frame_bound {
+	return Frame_bound(strconv.FormatUint(uint64(n), 10)+" PRECEDING")
+}
+
+func Following(n uint32) Frame_bound {
+	return Frame_bound(strconv.FormatUint(uint64(n), 10)+" FOLLOWING")
+}
+
+//	Window function as a select field
+func (q *Select_query) Select_window(alias string, f *Window_func) *Select_query {
+	q.select_fields = append(q.select_fields, select_field{
+		alias:	alias,
+		window:	f,
+	})
+	return q
+}
+
+//	Named window: "WINDOW name AS (...)"
+func (q *Select_query) Window(name string, spec *Window_spec) *Select_query {
+	q.windows = append(q.windows, select_window{name, spec})
+	return q
+}
+
+func (q *Select_query) window_error() error {
+	for _, s := range q.select_fields {
+		f := s.window
+		if f == nil {
+			continue
+		}
+		if s.alias == "" {
+			return fmt.Errorf("Window function %s must have an alias", f.function)
+		}
+		if f.name != "" && !q.has_window(f.name) {
+			return fmt.Errorf("Unknown window: %s", f.name)
+		}
+		if err := f.spec.frame_error(); err != nil {
+			return err
+		}
+	}
+	for i, w := range q.windows {
+		if w.name == "" || w.spec == nil {
+			return fmt.Errorf("Window must have a name and a spec")
+		}
+		for _, v := range q.windows[:i] {
+			if v.name == w.name {
+				return fmt.Errorf("Duplicate window: %s", w.name)
+			}
+		}
+		if err := w.spec.frame_error(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//	Frame bounds are written as is
+func (w *Window_spec) frame_error() error {
+	if w == nil || w.frame == "" {
+		return nil
+	}
+	if !w.frame_start.valid() {
+		return fmt.Errorf("Invalid window frame bound: %s", w.frame_start)
+	}
+	if !w.frame_end.valid() {
+		return fmt.Errorf("Invalid window frame bound: %s", w.frame_end)
+	}
+	return nil
+}
+
+//	Constants or "n PRECEDING" and "n FOLLOWING" (Preceding and Following)
+func (b Frame_bound) valid() bool {
+	switch b {
+	case Unbounded_preceding, Unbounded_following, Current_row:
+		return true
+	}
+	n, direction, _ := strings.Cut(string(b), " ")
+	if direction != "PRECEDING" && direction != "FOLLOWING" {
+		return false
+	}
+	_, err := strconv.ParseUint(n, 10, 32)
+	return err == nil
+}
+
+func (q *Select_query) has_window(name string) bool {
+	for _, w := range q.windows {
+		if w.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (q *Select_query) write_window_func(ctx *compiler, f *Window_func){
+	ctx.sb.WriteString(f.function)
+	ctx.sb.WriteByte('(')
+	if f.field != "" {
+		ctx.write_field(q.t, f.field)
+	}
+	if f.function == "LAG" || f.function == "LEAD" {
+		var buf [10]byte
+		ctx.sb.WriteString(", ")
+		ctx.sb.Write(strconv.AppendUint(buf[:0], uint64(f.offset), 10))
+		if f.use_value {
+			ctx.sb.WriteString(", ?")
+			ctx.append_data(f.value)
+		}
+	}
+	ctx.sb.WriteString(") OVER ")
+	if f.name != "" {
+		ctx.sb.WriteString(f.name)
+		return
+	}
+	ctx.sb.WriteByte('(')
+	if f.spec != nil {
+		q.write_window_spec(ctx, f.spec)
+	}
+	ctx.sb.WriteByte(')')
+}
+
+func (q *Select_query) write_window_spec(ctx *compiler, w *Window_spec){
+	var space bool
+	if len(w.partition) != 0 {
+		ctx.sb.WriteString("PARTITION BY ")
+		for i, v := range w.partition {
+			if i > 0 {
+				ctx.sb.WriteString(", ")
+			}
+			ctx.write_field(q.t, v)
+		}
+		space = true
+	}
+	if len(w.order) != 0 {
+		if space {
+			ctx.sb.WriteByte(' ')
+		}
+		ctx.sb.WriteString("ORDER BY ")
+		for i, v := range w.order {
+			if i > 0 {
+				ctx.sb.WriteString(", ")
+			}
+			ctx.write_field(q.t, v)
+		}
+		space = true
+	}
+	if w.frame != "" {
+		if space {
+			ctx.sb.WriteByte(' ')
+		}
+		ctx.sb.WriteString(w.frame)
+		ctx.sb.WriteString(" BETWEEN ")
+		ctx.sb.WriteString(string(w.frame_start))
+		ctx.sb.WriteString(" AND ")
+		ctx.sb.WriteString(string(w.frame_end))
+	}
+}
+
+func (q *Select_query) compile_windows(ctx *compiler){
+	if len(q.windows) == 0 {
+		return
+	}
+	
+	ctx.sb.WriteString("WINDOW ")
+	for i, w := range q.windows {
+		if i > 0 {
+			ctx.sb.WriteString(", ")
+		}
+		ctx.sb.WriteString(w.name)
+		ctx.sb.WriteString(" AS (")
+		q.write_window_spec(ctx, w.spec)
+		ctx.sb.WriteByte(')')
+	}
+	ctx.sb.WriteByte('\n')
+}
+
+func (w *Window_spec) collect_aliases(list alias_collect){
+	for _, f := range w.partition {
+		list.apply(f)
+	}
+	for _, f := range w.order {
+		list.apply(f)
+	}
+}
\ No newline at end of file
@@ -0,0 +1,195 @@
+package sqlcheck
+
+import (
+	"os"
+	"sync"
+	"strings"
+	"go/ast"
+	"go/types"
+	"go/constant"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+	"github.com/clarkk/go-dbd"
+	"github.com/clarkk/go-dbd/sqlc"
+)
+
+const sqlc_path = "github.com/clarkk/go-dbd/sqlc"
+
+var (
+	Analyzer = &analysis.Analyzer{
+		Name:		"sqlcheck",
+		Doc:		"check constant sqlc query builder calls against a schema snapshot\n\nReports unknown tables and columns (with -schema), join alias collisions and updates/deletes without a where clause.",
+		Run:		run,
+		Requires:	[]*analysis.Analyzer{inspect.Analyzer},
+	}
+	
+	schema_file	string
+	schema_once	sync.Once
+	schema		*dbd.Snapshot
+	schema_err	error
+)
+
+func init(){
+	Analyzer.Flags.StringVar(&schema_file, "schema", "", "schema snapshot file (dbd.Snapshot JSON)")
+}
+
+func run(pass *analysis.Pass) (any, error){
+	if err := load_schema(); err != nil {
+		return nil, err
+	}
+	
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	insp.WithStack([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node, push bool, stack []ast.Node) bool {
+		if !push {
+			return true
+		}
+		call := n.(*ast.CallExpr)
+		if !is_query_type(pass.TypesInfo.TypeOf(call)) {
+			return true
+		}
+		check_query(pass, call, used_directly(call, stack))
+		//	Sub queries are checked as part of the outermost query
+		return false
+	})
+	return nil, nil
+}
+
+func check_query(pass *analysis.Pass, call *ast.CallExpr, used bool){
+	e := &evaluator{
+		info: pass.TypesInfo,
+	}
+	v, ok := e.eval_call(call)
+	if !ok {
+		return
+	}
+	query, ok := v.Interface().(sqlc.SQL)
+	if !ok {
+		return
+	}
+	
+	if schema != nil {
+		if validator, ok := query.(sqlc.Validator); ok {
+			for _, err := range unwrap(validator.Validate(schema)) {
+				//	Joins with non-constant arguments are unknown
+				if e.incomplete && strings.HasPrefix(err.Error(), "Unknown table alias") {
+					continue
+				}
+				pass.Reportf(call.Pos(), "sqlc: %s", err)
+			}
+		}
+	}
+	
+	if err := compile(query); err != nil {
+		msg := err.Error()
+		switch {
+		case strings.HasSuffix(msg, "without where"):
+			//	Where clause might be applied later
+			if !used || e.incomplete {
+				return
+			}
+		case strings.HasPrefix(msg, "Join table short already used"):
+		default:
+			if e.incomplete {
+				return
+			}
+		}
+		pass.Reportf(call.Pos(), "sqlc: %s", msg)
+	}
+}
+
+//	Query is passed directly to a function (i.e. dbd.Exec) or compiled
+func used_directly(call *ast.CallExpr, stack []ast.Node) bool {
+	if len(stack) < 2 {
+		return false
+	}
+	switch parent := stack[len(stack)-2].(type) {
+	case *ast.CallExpr:
+		for _, arg := range parent.Args {
+			if arg == call {
+				return true
+			}
+		}
+	case *ast.SelectorExpr:
+		return parent.Sel.Name == "Compile"
+	}
+	return false
+}
+
+func is_query_type(t types.Type) bool {
+	if t == nil {
+		return false
+	}
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := t.(*types.Named)
+	if !ok || named.Obj().Pkg() == nil || named.Obj().Pkg().Path() != sqlc_path {
+		return false
+	}
+	return strings.HasSuffix(named.Obj().Name(), "_query")
+}
+
+func is_sqlc_func(info *types.Info, call *ast.CallExpr) (*types.Func, bool){
+	fn := typeutil.StaticCallee(info, call)
+	if fn == nil || fn.Pkg() == nil || fn.Pkg().Path() != sqlc_path {
+		return nil, false
+	}
+	return fn, true
+}
+
+func compile(query sqlc.SQL) (err error){
+	defer func(){
+		if r := recover(); r != nil {
+			err = nil
+		}
+	}()
+	_, _, err = query.Compile()
+	return err
+}
+
+func load_schema() error {
+	schema_once.Do(func(){
+		if schema_file == "" {
+			return
+		}
+		f, err := os.Open(schema_file)
+		if err != nil {
+			schema_err = err
+			return
+		}
+		defer f.Close()
+		schema, schema_err = dbd.Read_snapshot(f)
+	})
+	return schema_err
+}
+
+func unwrap(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
+
+func constant_value(v constant.Value) any {
+	switch v.Kind() {
+	case constant.String:
+		return constant.StringVal(v)
+	case constant.Bool:
+		return constant.BoolVal(v)
+	case constant.Int:
+		if i, ok := constant.Int64Val(v); ok {
+			return i
+		}
+		u, _ := constant.Uint64Val(v)
+		return u
+	case constant.Float:
+		f, _ := constant.Float64Val(v)
+		return f
+	}
+	return nil
+}
\ No newline at end of file
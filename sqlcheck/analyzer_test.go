@@ -0,0 +1,18 @@
+package sqlcheck
+
+/*
+	Test
+	# go test . -v
+*/
+
+import (
+	"testing"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func Test_analyzer(t *testing.T){
+	if err := Analyzer.Flags.Set("schema", "testdata/schema.json"); err != nil {
+		t.Fatal(err)
+	}
+	analysistest.Run(t, analysistest.TestData(), Analyzer, "./a")
+}
\ No newline at end of file
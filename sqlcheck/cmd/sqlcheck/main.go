@@ -0,0 +1,11 @@
+//	Usage: go vet -vettool=$(which sqlcheck) -sqlcheck.schema=schema.json ./...
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/unitchecker"
+	"github.com/clarkk/go-dbd/sqlcheck"
+)
+
+func main(){
+	unitchecker.Main(sqlcheck.Analyzer)
+}
\ No newline at end of file
@@ -0,0 +1,257 @@
+package sqlcheck
+
+import (
+	"reflect"
+	"go/ast"
+	"go/types"
+	"github.com/clarkk/go-dbd/sqlc"
+)
+
+//	Package functions that can be evaluated
+var funcs = map[string]any{
+	"Select":		sqlc.Select,
+	"Select_id":	sqlc.Select_id,
+	"Update":		sqlc.Update,
+	"Update_id":	sqlc.Update_id,
+	"Delete":		sqlc.Delete,
+	"Delete_id":	sqlc.Delete_id,
+	"Insert":		sqlc.Insert,
+	"Inserts":		sqlc.Inserts,
+	"Union":		sqlc.Union,
+	"Union_all":	sqlc.Union_all,
+	"Where":		sqlc.Where,
+	"And":			sqlc.And,
+	"Or":			sqlc.Or,
+	"Not":			sqlc.Not,
+	"Fields":		sqlc.Fields,
+	"Json_array":	sqlc.Json_array,
+	"Json_object":	sqlc.Json_object,
+	"Json_objectagg":	sqlc.Json_objectagg,
+	"With":			sqlc.With,
+	"With_recursive":	sqlc.With_recursive,
+	"Window":		sqlc.Window,
+	"Row_number":	sqlc.Row_number,
+	"Rank":			sqlc.Rank,
+	"Dense_rank":	sqlc.Dense_rank,
+	"Lag":			sqlc.Lag,
+	"Lead":			sqlc.Lead,
+	"Sum":			sqlc.Sum,
+	"Avg":			sqlc.Avg,
+}
+
+//	Rebuild sqlc queries from constant arguments
+type evaluator struct {
+	info		*types.Info
+	incomplete	bool	//	Method calls with non-constant arguments are skipped
+}
+
+func (e *evaluator) eval_call(call *ast.CallExpr) (v reflect.Value, ok bool){
+	fn, ok := is_sqlc_func(e.info, call)
+	if !ok {
+		return reflect.Value{}, false
+	}
+	
+	//	Builders panic on invalid input
+	defer func(){
+		if r := recover(); r != nil {
+			v, ok = reflect.Value{}, false
+		}
+	}()
+	
+	var (
+		f		reflect.Value
+		recv	reflect.Value
+	)
+	if fn.Signature().Recv() != nil {
+		sel, is_sel := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+		if !is_sel {
+			return reflect.Value{}, false
+		}
+		if recv, ok = e.eval(sel.X, nil); !ok {
+			return reflect.Value{}, false
+		}
+		if f = recv.MethodByName(fn.Name()); !f.IsValid() {
+			return reflect.Value{}, false
+		}
+	} else {
+		pkg_func, found := funcs[fn.Name()]
+		if !found {
+			return reflect.Value{}, false
+		}
+		f = reflect.ValueOf(pkg_func)
+	}
+	
+	args, ok := e.eval_args(call, f.Type())
+	if !ok {
+		if recv.IsValid() {
+			//	Skip method call and continue the chain
+			e.incomplete = true
+			return recv, true
+		}
+		return reflect.Value{}, false
+	}
+	
+	var results []reflect.Value
+	if f.Type().IsVariadic() {
+		results = f.CallSlice(args)
+	} else {
+		results = f.Call(args)
+	}
+	if len(results) == 0 || results[0].Kind() != reflect.Pointer || results[0].IsNil() {
+		return reflect.Value{}, false
+	}
+	return results[0], true
+}
+
+func (e *evaluator) eval_args(call *ast.CallExpr, ftype reflect.Type) ([]reflect.Value, bool){
+	num := ftype.NumIn()
+	if call.Ellipsis.IsValid() {
+		return nil, false
+	}
+	
+	args := make([]reflect.Value, num)
+	for i := range num {
+		typ := ftype.In(i)
+		
+		//	Collect variadic arguments in a slice
+		if ftype.IsVariadic() && i == num-1 {
+			rest := reflect.MakeSlice(typ, 0, len(call.Args)-i)
+			for _, arg := range call.Args[i:] {
+				v, ok := e.eval_value(arg, typ.Elem())
+				if !ok {
+					return nil, false
+				}
+				rest = reflect.Append(rest, v)
+			}
+			args[i] = rest
+			break
+		}
+		
+		if i >= len(call.Args) {
+			return nil, false
+		}
+		v, ok := e.eval_value(call.Args[i], typ)
+		if !ok {
+			return nil, false
+		}
+		args[i] = v
+	}
+	return args, true
+}
+
+//	Values (type any) are not needed to check the query
+func (e *evaluator) eval_value(expr ast.Expr, typ reflect.Type) (reflect.Value, bool){
+	if v, ok := e.eval(expr, typ); ok {
+		return v, true
+	}
+	if typ.Kind() == reflect.Interface {
+		return reflect.Zero(typ), true
+	}
+	return reflect.Value{}, false
+}
+
+func (e *evaluator) eval(expr ast.Expr, typ reflect.Type) (reflect.Value, bool){
+	expr = ast.Unparen(expr)
+	tv := e.info.Types[expr]
+	
+	if tv.IsNil() && typ != nil {
+		switch typ.Kind() {
+		case reflect.Interface, reflect.Pointer, reflect.Slice, reflect.Map:
+			return reflect.Zero(typ), true
+		}
+		return reflect.Value{}, false
+	}
+	
+	if tv.Value != nil {
+		if typ == nil {
+			return reflect.Value{}, false
+		}
+		v := reflect.ValueOf(constant_value(tv.Value))
+		if !v.IsValid() || !v.CanConvert(typ) {
+			return reflect.Value{}, false
+		}
+		return v.Convert(typ), true
+	}
+	
+	switch x := expr.(type) {
+	case *ast.CallExpr:
+		v, ok := e.eval_call(x)
+		if !ok || (typ != nil && !v.Type().AssignableTo(typ)) {
+			return reflect.Value{}, false
+		}
+		return v, true
+		
+	case *ast.CompositeLit:
+		if typ == nil {
+			return reflect.Value{}, false
+		}
+		return e.eval_composite(x, typ)
+	}
+	return reflect.Value{}, false
+}
+
+func (e *evaluator) eval_composite(lit *ast.CompositeLit, typ reflect.Type) (reflect.Value, bool){
+	switch typ.Kind() {
+	case reflect.Slice:
+		s := reflect.MakeSlice(typ, 0, len(lit.Elts))
+		for _, elt := range lit.Elts {
+			if _, is_kv := elt.(*ast.KeyValueExpr); is_kv {
+				return reflect.Value{}, false
+			}
+			v, ok := e.eval_value(elt, typ.Elem())
+			if !ok {
+				return reflect.Value{}, false
+			}
+			s = reflect.Append(s, v)
+		}
+		return s, true
+		
+	case reflect.Map:
+		m := reflect.MakeMapWithSize(typ, len(lit.Elts))
+		for _, elt := range lit.Elts {
+			kv, is_kv := elt.(*ast.KeyValueExpr)
+			if !is_kv {
+				return reflect.Value{}, false
+			}
+			k, ok := e.eval(kv.Key, typ.Key())
+			if !ok {
+				return reflect.Value{}, false
+			}
+			v, ok := e.eval_value(kv.Value, typ.Elem())
+			if !ok {
+				return reflect.Value{}, false
+			}
+			m.SetMapIndex(k, v)
+		}
+		return m, true
+		
+	case reflect.Struct:
+		s := reflect.New(typ).Elem()
+		for i, elt := range lit.Elts {
+			var (
+				field	reflect.Value
+				value	= elt
+			)
+			if kv, is_kv := elt.(*ast.KeyValueExpr); is_kv {
+				ident, is_ident := kv.Key.(*ast.Ident)
+				if !is_ident {
+					return reflect.Value{}, false
+				}
+				field	= s.FieldByName(ident.Name)
+				value	= kv.Value
+			} else if i < s.NumField() {
+				field = s.Field(i)
+			}
+			if !field.IsValid() || !field.CanSet() {
+				return reflect.Value{}, false
+			}
+			v, ok := e.eval_value(value, field.Type())
+			if !ok {
+				return reflect.Value{}, false
+			}
+			field.Set(v)
+		}
+		return s, true
+	}
+	return reflect.Value{}, false
+}
\ No newline at end of file
@@ -0,0 +1,18 @@
+module github.com/clarkk/go-dbd/sqlcheck
+
+go 1.26.0
+
+require (
+	github.com/clarkk/go-dbd v0.0.0
+	golang.org/x/tools v0.51.0
+)
+
+require (
+	filippo.io/edwards25519 v1.1.0 // indirect
+	github.com/go-errors/errors v1.5.1 // indirect
+	github.com/go-sql-driver/mysql v1.9.3 // indirect
+	golang.org/x/mod v0.41.0 // indirect
+	golang.org/x/sync v0.23.0 // indirect
+)
+
+replace github.com/clarkk/go-dbd => ../
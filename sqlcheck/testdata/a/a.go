@@ -0,0 +1,58 @@
+package a
+
+import "github.com/clarkk/go-dbd/sqlc"
+
+func exec(query sqlc.SQL){}
+
+func queries(name string, where *sqlc.Where_clause){
+	sqlc.Select("user"). // want `sqlc: Unknown column: user.emial`
+		Select([]string{
+			"id",
+			"emial",
+		}).
+		Where(sqlc.Where().
+			Eq("name", name),
+		)
+	
+	sqlc.Select("user"). // want `sqlc: Join table short already used: c \(client\)`
+		Select([]string{
+			"id",
+			"c.timeout",
+		}).
+		Left_join("client", "c", "id", "client_id").
+		Left_join("client", "c", "id", "client_id")
+	
+	sqlc.Select("user").
+		Select([]string{
+			"id",
+			"c.timeout",
+		}).
+		Left_join("client", "c", "id", "client_id").
+		Where(where)
+	
+	sqlc.Select("user").
+		Select([]string{
+			"id",
+		}).
+		Left_join("client", "c", "id", name).
+		Where(sqlc.Where().
+			Eq("c.timeout", 1).
+			Eq("email", 1),
+		)
+	
+	exec(sqlc.Update("user"). // want `sqlc: Update without where`
+		Fields(sqlc.Map{
+			"name":	name,
+		}))
+	
+	exec(sqlc.Delete("users")) // want `sqlc: Unknown table: users` `sqlc: Delete without where`
+	
+	exec(sqlc.Delete("user").Where(where))
+	
+	q := sqlc.Update("user").
+		Fields(sqlc.Map{
+			"name":	name,
+		})
+	q.Where(where)
+	exec(q)
+}
\ No newline at end of file
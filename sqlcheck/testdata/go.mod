@@ -0,0 +1,7 @@
+module example
+
+go 1.26.0
+
+require github.com/clarkk/go-dbd v0.0.0
+
+replace github.com/clarkk/go-dbd => ../..
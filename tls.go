@@ -0,0 +1,79 @@
+package dbd
+
+import (
+	"fmt"
+	"strconv"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/go-sql-driver/mysql"
+)
+
+//	TLS connection (system roots are used unless a CA is given)
+type TLS_config struct {
+	CA				[]byte	//	CA certificate PEM
+	System_roots	bool	//	Trust system roots in addition to the CA
+	Cert			[]byte	//	Client certificate PEM
+	Key				[]byte	//	Client key PEM
+	Server_name		string	//	Defaults to the TCP host
+	Skip_verify		bool	//	Do not verify the server certificate (dev only)
+}
+
+//	Register the TLS config with the driver under a generated name
+func (d DSN) TLS(conf TLS_config) (DSN, error){
+	tls_conf, err := conf.tls_config()
+	if err != nil {
+		return DSN{}, err
+	}
+	
+	name := conf.name()
+	if err := mysql.RegisterTLSConfig(name, tls_conf); err != nil {
+		return DSN{}, fmt.Errorf("Unable to register TLS config: %w", err)
+	}
+	
+	d = d.clone()
+	d.cfg.TLSConfig = name
+	return d, nil
+}
+
+func (c TLS_config) tls_config() (*tls.Config, error){
+	conf := &tls.Config{
+		ServerName:			c.Server_name,
+		InsecureSkipVerify:	c.Skip_verify,
+		MinVersion:			tls.VersionTLS12,
+	}
+	
+	if len(c.CA) != 0 {
+		pool := x509.NewCertPool()
+		if c.System_roots {
+			if system, err := x509.SystemCertPool(); err == nil {
+				pool = system
+			}
+		}
+		if !pool.AppendCertsFromPEM(c.CA) {
+			return nil, fmt.Errorf("Invalid TLS CA certificate PEM")
+		}
+		conf.RootCAs = pool
+	}
+	
+	if len(c.Cert) != 0 || len(c.Key) != 0 {
+		cert, err := tls.X509KeyPair(c.Cert, c.Key)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid TLS client certificate: %w", err)
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+	return conf, nil
+}
+
+//	Same config is registered under the same name
+func (c TLS_config) name() string {
+	h := sha256.New()
+	for _, b := range [][]byte{c.CA, c.Cert, c.Key, []byte(c.Server_name)} {
+		h.Write([]byte(strconv.Itoa(len(b))+":"))
+		h.Write(b)
+	}
+	h.Write([]byte(strconv.FormatBool(c.System_roots)+strconv.FormatBool(c.Skip_verify)))
+	return "dbd_"+hex.EncodeToString(h.Sum(nil))[:16]
+}
\ No newline at end of file
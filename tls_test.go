@@ -0,0 +1,159 @@
+package dbd
+
+/*
+	Test
+	# go test . -v
+*/
+
+import (
+	"net"
+	"time"
+	"testing"
+	"math/big"
+	"crypto/tls"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/pem"
+	"crypto/x509/pkix"
+)
+
+func Test_tls(t *testing.T){
+	ca, ca_key, ca_pem, _ := test_cert(t, "ca", nil, nil)
+	_, _, server_pem, server_key_pem := test_cert(t, "localhost", ca, ca_key)
+	_, _, client_pem, client_key_pem := test_cert(t, "client", ca, ca_key)
+	
+	server, err := tls.X509KeyPair(server_pem, server_key_pem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	
+	client_pool := x509.NewCertPool()
+	client_pool.AddCert(ca)
+	
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates:	[]tls.Certificate{server},
+		ClientAuth:		tls.RequireAndVerifyClientCert,
+		ClientCAs:		client_pool,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func(){
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+	port := ln.Addr().(*net.TCPAddr).Port
+	
+	handshake := func(conf TLS_config) error {
+		d, err := NewDSN("user", "pass", "db", "").TLS(conf)
+		if err != nil {
+			return err
+		}
+		//	Resolve the registered TLS config like the driver does
+		parsed, err := Parse_DSN(d.TCP("localhost", port))
+		if err != nil {
+			return err
+		}
+		conn, err := tls.Dial("tcp", ln.Addr().String(), parsed.Config().TLS)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		return conn.Handshake()
+	}
+	
+	t.Run("custom CA and client cert", func(t *testing.T){
+		if err := handshake(TLS_config{CA: ca_pem, Cert: client_pem, Key: client_key_pem}); err != nil {
+			t.Fatal(err)
+		}
+	})
+	
+	t.Run("system roots and custom CA", func(t *testing.T){
+		if err := handshake(TLS_config{CA: ca_pem, System_roots: true, Cert: client_pem, Key: client_key_pem}); err != nil {
+			t.Fatal(err)
+		}
+	})
+	
+	t.Run("unknown authority", func(t *testing.T){
+		if err := handshake(TLS_config{Cert: client_pem, Key: client_key_pem}); err == nil {
+			t.Fatalf("Handshake with system roots must fail")
+		}
+	})
+	
+	t.Run("server name", func(t *testing.T){
+		if err := handshake(TLS_config{CA: ca_pem, Cert: client_pem, Key: client_key_pem, Server_name: "db.example.com"}); err == nil {
+			t.Fatalf("Handshake with wrong server name must fail")
+		}
+	})
+	
+	t.Run("skip verify", func(t *testing.T){
+		if err := handshake(TLS_config{Skip_verify: true, Cert: client_pem, Key: client_key_pem}); err != nil {
+			t.Fatal(err)
+		}
+	})
+	
+	t.Run("invalid PEM", func(t *testing.T){
+		if _, err := NewDSN("user", "pass", "db", "").TLS(TLS_config{CA: []byte("invalid")}); err == nil {
+			t.Fatalf("Invalid CA must fail")
+		}
+		if _, err := NewDSN("user", "pass", "db", "").TLS(TLS_config{Cert: client_pem}); err == nil {
+			t.Fatalf("Client cert without key must fail")
+		}
+	})
+	
+	t.Run("generated name", func(t *testing.T){
+		conf := TLS_config{CA: ca_pem}
+		a, _ := NewDSN("user", "pass", "db", "").TLS(conf)
+		b, _ := NewDSN("user", "pass", "db", "").TLS(conf)
+		c, _ := NewDSN("user", "pass", "db", "").TLS(TLS_config{CA: ca_pem, Skip_verify: true})
+		if a.Config().TLSConfig != b.Config().TLSConfig || a.Config().TLSConfig == c.Config().TLSConfig {
+			t.Fatalf("TLS config names got: %s %s %s", a.Config().TLSConfig, b.Config().TLSConfig, c.Config().TLSConfig)
+		}
+	})
+}
+
+//	Self-signed CA (without parent) or certificate signed by the CA
+func test_cert(t *testing.T, name string, parent *x509.Certificate, parent_key *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, []byte, []byte){
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:	big.NewInt(time.Now().UnixNano()),
+		Subject:		pkix.Name{CommonName: name},
+		NotBefore:		time.Now().Add(-time.Hour),
+		NotAfter:		time.Now().Add(time.Hour),
+		KeyUsage:		x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:	[]x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		template.IsCA					= true
+		template.BasicConstraintsValid	= true
+		parent, parent_key				= template, key
+	} else {
+		template.DNSNames = []string{name}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parent_key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key_der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: key_der})
+}
\ No newline at end of file
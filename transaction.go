@@ -86,7 +86,7 @@ func (t *Tx) Exec(query sqlc.SQL) (sql.Result, error){
 		panic("DB transaction execute: No active transaction")
 	}
 	
-	sql, data, err := query.Compile()
+	sql, data, err := compile(query)
 	if err != nil {
 		return nil, &Error{"DB transaction execute compile: "+err.Error(), errors.Wrap(err, 0).ErrorStack()}
 	}
@@ -102,6 +102,9 @@ func (t *Tx) Exec(query sqlc.SQL) (sql.Result, error){
 		if ctx_canceled(err) {
 			return nil, &Timeout_error{msg, stack}
 		}
+		if lock_unavailable(err) {
+			return nil, &Lock_error{msg, stack}
+		}
 		return nil, &Error{msg, stack}
 	}
 	return result, nil
@@ -112,7 +115,7 @@ func (t *Tx) Query_row(query sqlc.SQL, scan []any) (bool, error){
 		panic("DB transaction query row: No active transaction")
 	}
 	
-	sql, data, err := query.Compile()
+	sql, data, err := compile(query)
 	if err != nil {
 		return false, &Error{"DB transaction query row compile: "+err.Error(), errors.Wrap(err, 0).ErrorStack()}
 	}
@@ -130,6 +133,9 @@ func (t *Tx) Query_row(query sqlc.SQL, scan []any) (bool, error){
 		if ctx_canceled(err) {
 			return false, &Timeout_error{msg, stack}
 		}
+		if lock_unavailable(err) {
+			return false, &Lock_error{msg, stack}
+		}
 		return false, &Error{msg, stack}
 	}
 	return false, nil
@@ -140,7 +146,7 @@ func (t *Tx) Query(query sqlc.SQL) (*sql.Rows, error){
 		panic("DB transaction query: No active transaction")
 	}
 	
-	sql, data, err := query.Compile()
+	sql, data, err := compile(query)
 	if err != nil {
 		return nil, &Error{"DB transaction query compile: "+err.Error(), errors.Wrap(err, 0).ErrorStack()}
 	}
@@ -156,6 +162,9 @@ func (t *Tx) Query(query sqlc.SQL) (*sql.Rows, error){
 		if ctx_canceled(err) {
 			return nil, &Timeout_error{msg, stack}
 		}
+		if lock_unavailable(err) {
+			return nil, &Lock_error{msg, stack}
+		}
 		return nil, &Error{msg, stack}
 	}
 	return rows, nil
@@ -167,7 +176,7 @@ func (t *Tx) Insert(query sqlc.SQL) (uint64, error){
 	}
 	
 	var id uint64
-	sql, data, err := query.Compile()
+	sql, data, err := compile(query)
 	if err != nil {
 		return id, &Error{"DB transaction insert compile: "+err.Error(), errors.Wrap(err, 0).ErrorStack()}
 	}
@@ -182,6 +191,9 @@ func (t *Tx) Insert(query sqlc.SQL) (uint64, error){
 		if ctx_canceled(err) {
 			return 0, &Timeout_error{msg, stack}
 		}
+		if lock_unavailable(err) {
+			return 0, &Lock_error{msg, stack}
+		}
 		return 0, &Error{msg, stack}
 	}
 	return id, nil
@@ -192,7 +204,7 @@ func (t *Tx) Insert_no_return(query sqlc.SQL) error {
 		panic("DB transaction insert no return: No active transaction")
 	}
 	
-	sql, data, err := query.Compile()
+	sql, data, err := compile(query)
 	if err != nil {
 		return &Error{"DB transaction insert no return compile: "+err.Error(), errors.Wrap(err, 0).ErrorStack()}
 	}
@@ -208,6 +220,9 @@ func (t *Tx) Insert_no_return(query sqlc.SQL) error {
 		if ctx_canceled(err) {
 			return &Timeout_error{msg, stack}
 		}
+		if lock_unavailable(err) {
+			return &Lock_error{msg, stack}
+		}
 		return &Error{msg, stack}
 	}
 	return nil
@@ -218,7 +233,7 @@ func (t *Tx) Update(query sqlc.SQL) error {
 		panic("DB transaction update: No active transaction")
 	}
 	
-	sql, data, err := query.Compile()
+	sql, data, err := compile(query)
 	if err != nil {
 		return &Error{"DB transaction update compile: "+err.Error(), errors.Wrap(err, 0).ErrorStack()}
 	}
@@ -233,6 +248,9 @@ func (t *Tx) Update(query sqlc.SQL) error {
 		if ctx_canceled(err) {
 			return &Timeout_error{msg, stack}
 		}
+		if lock_unavailable(err) {
+			return &Lock_error{msg, stack}
+		}
 		return &Error{msg, stack}
 	}
 	return nil
@@ -243,7 +261,7 @@ func (t *Tx) Delete(query sqlc.SQL) (bool, error){
 		panic("DB transaction delete: No active transaction")
 	}
 	
-	sql, data, err := query.Compile()
+	sql, data, err := compile(query)
 	if err != nil {
 		return false, &Error{"DB transaction delete compile: "+err.Error(), errors.Wrap(err, 0).ErrorStack()}
 	}
@@ -262,6 +280,9 @@ func (t *Tx) Delete(query sqlc.SQL) (bool, error){
 		if ctx_canceled(err) {
 			return false, &Timeout_error{msg, stack}
 		}
+		if lock_unavailable(err) {
+			return false, &Lock_error{msg, stack}
+		}
 		return false, &Error{msg, stack}
 	}
 	return false, nil